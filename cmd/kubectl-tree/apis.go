@@ -39,6 +39,16 @@ func (rm *resourceMap) lookup(s string) []apiResource {
 
 func (rm *resourceMap) resources() []apiResource { return rm.list }
 
+// lookupKind returns the API resource of the kind in the group, regardless of its version.
+func (rm *resourceMap) lookupKind(gk schema.GroupKind) (apiResource, bool) {
+	for _, a := range rm.list {
+		if a.gv.Group == gk.Group && a.r.Kind == gk.Kind {
+			return a, true
+		}
+	}
+	return apiResource{}, false
+}
+
 func fullAPIName(a apiResource) string {
 	sgv := a.GroupVersionResource()
 	return strings.Join([]string{sgv.Resource, sgv.Version, sgv.Group}, ".")
@@ -143,7 +153,8 @@ func findAPIs(client discovery.DiscoveryInterface, apiGroups, resources []string
 				continue
 			}
 			// NOTE: if a intermediate owner is excluded that will break the chain, even if the leaf is included
-			// for example --resources=deployments,pods will return nothing because replicasets are not included
+			// for example --resources=deployments,pods will return nothing because replicasets are not included,
+			// unless --include-owners is set (see getResourcesWithOwners)
 			if !matchResources(resources, apiRes) {
 				klog.V(5).Infof("    api (%s) doesn't match any resource pattern, skipping: %v", apiRes.Name, apiRes.Verbs)
 				continue
@@ -0,0 +1,72 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/client-go/util/jsonpath"
+)
+
+const outputCustomColumns = "custom-columns"
+
+// noValue is shown in custom columns for fields that the object doesn't have, like kubectl does.
+const noValue = "<none>"
+
+// treeColumn is a column of the tree table with a value computed from each object by a JSONPath expression.
+type treeColumn struct {
+	header string
+	path   *jsonpath.JSONPath
+}
+
+// parseColumns parses a comma-separated list of column specifications in the HEADER:JSONPATH form, such as
+// "REPLICAS:.spec.replicas,IMAGE:.spec.containers[*].image".
+func parseColumns(spec string) ([]treeColumn, error) {
+	var out []treeColumn
+	for _, c := range strings.Split(spec, ",") {
+		header, expr, ok := strings.Cut(c, ":")
+		if !ok || header == "" || expr == "" {
+			return nil, fmt.Errorf("column %q is not in HEADER:JSONPATH form", c)
+		}
+		p := jsonpath.New(header).AllowMissingKeys(true)
+		if err := p.Parse(relaxedJSONPath(expr)); err != nil {
+			return nil, fmt.Errorf("invalid JSONPath expression for column %s: %w", header, err)
+		}
+		out = append(out, treeColumn{header: header, path: p})
+	}
+	return out, nil
+}
+
+// relaxedJSONPath turns expressions like ".spec.replicas" or "spec.replicas" into a JSONPath template
+// ("{.spec.replicas}"), like kubectl does for custom columns.
+func relaxedJSONPath(expr string) string {
+	expr = strings.TrimSuffix(strings.TrimPrefix(strings.TrimSpace(expr), "{"), "}")
+	if !strings.HasPrefix(expr, ".") {
+		expr = "." + expr
+	}
+	return "{" + expr + "}"
+}
+
+// value returns the result of the column's expression for obj, with multiple results separated by commas.
+func (c treeColumn) value(obj unstructured.Unstructured) string {
+	results, err := c.path.FindResults(obj.Object)
+	if err != nil {
+		return noValue
+	}
+	var values []string
+	for _, r := range results {
+		for _, v := range r {
+			var b bytes.Buffer
+			if err := c.path.PrintResults(&b, []reflect.Value{v}); err != nil {
+				continue
+			}
+			values = append(values, b.String())
+		}
+	}
+	if len(values) == 0 {
+		return noValue
+	}
+	return strings.Join(values, ",")
+}
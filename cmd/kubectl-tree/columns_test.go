@@ -0,0 +1,52 @@
+package main
+
+import (
+	"testing"
+)
+
+func TestColumns(t *testing.T) {
+	pod := newTestObject("v1", "Pod", "app", "p1")
+	pod.SetLabels(map[string]string{"app": "web"})
+	pod.Object["spec"] = map[string]interface{}{
+		"nodeName": "node-1",
+		"containers": []interface{}{
+			map[string]interface{}{"name": "app", "image": "app:v1"},
+			map[string]interface{}{"name": "proxy", "image": "envoy:v1"},
+		},
+	}
+	pod.Object["status"] = map[string]interface{}{"restartCount": int64(3)}
+
+	tests := []struct {
+		spec    string
+		headers []string
+		want    []string
+		wantErr bool
+	}{
+		{spec: "NODE:.spec.nodeName", want: []string{"node-1"}},
+		{spec: "Node:spec.nodeName,APP:.metadata.labels.app", headers: []string{"Node", "APP"}, want: []string{"node-1", "web"}},
+		{spec: "IMAGES:.spec.containers[*].image", want: []string{"app:v1,envoy:v1"}},
+		{spec: "RESTARTS:{.status.restartCount}", want: []string{"3"}},
+		{spec: "MISSING:.spec.replicas", want: []string{noValue}},
+		{spec: "NODE", wantErr: true},
+		{spec: "BAD:.spec[", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.spec, func(t *testing.T) {
+			cols, err := parseColumns(tt.spec)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseColumns() error = %v, want error: %v", err, tt.wantErr)
+			}
+			if len(cols) != len(tt.want) {
+				t.Fatalf("got %d columns, want %d", len(cols), len(tt.want))
+			}
+			for i, c := range cols {
+				if tt.headers != nil && c.header != tt.headers[i] {
+					t.Errorf("got header %q, want %q", c.header, tt.headers[i])
+				}
+				if got := c.value(pod); got != tt.want[i] {
+					t.Errorf("column %s = %q, want %q", c.header, got, tt.want[i])
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,189 @@
+package main
+
+import (
+	stderrors "errors"
+	"fmt"
+	"io"
+	"slices"
+	"sync"
+
+	"github.com/fatih/color"
+	"github.com/gosuri/uitable"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+	"k8s.io/klog"
+	"k8s.io/utils/ptr"
+)
+
+// configFlagsFor returns kubeconfig flags that use the specified context, with the same kubeconfig file, namespace,
+// impersonation and request settings as the command line flags. The flags that select a cluster or credentials
+// (e.g. --cluster or --token) are not copied, as they belong to the current context.
+func configFlagsFor(context string) *genericclioptions.ConfigFlags {
+	f := genericclioptions.NewConfigFlags(true)
+	f.Context = ptr.To(context)
+	f.CacheDir = cf.CacheDir
+	f.KubeConfig = cf.KubeConfig
+	f.Namespace = cf.Namespace
+	f.Impersonate = cf.Impersonate
+	f.ImpersonateUID = cf.ImpersonateUID
+	f.ImpersonateGroup = cf.ImpersonateGroup
+	f.Timeout = cf.Timeout
+	f.DisableCompression = cf.DisableCompression
+	return f
+}
+
+// contextName returns the name of the kubeconfig context used by the config flags.
+func contextName(f *genericclioptions.ConfigFlags) string {
+	if v := ptr.Deref(f.Context, ""); v != "" {
+		return v
+	}
+	raw, err := f.ToRawKubeConfigLoader().RawConfig()
+	if err != nil {
+		return ""
+	}
+	return raw.CurrentContext
+}
+
+// kubeconfigContexts returns the names of all contexts in the kubeconfig, sorted.
+func kubeconfigContexts(f *genericclioptions.ConfigFlags) ([]string, error) {
+	raw, err := f.ToRawKubeConfigLoader().RawConfig()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load kubeconfig: %w", err)
+	}
+	var out []string
+	for name := range raw.Contexts {
+		out = append(out, name)
+	}
+	slices.Sort(out)
+	return out, nil
+}
+
+// contextTrees are the trees queried from a kubeconfig context with the options to print them, or the error of the
+// query.
+type contextTrees struct {
+	context string
+	objs    objectDirectory
+	roots   []unstructured.Unstructured
+	single  bool
+	opts    treeOptions
+	err     error
+}
+
+// queryContexts runs the query in each of the contexts concurrently, and returns the trees in the order of contexts.
+// The resource types in opts are resolved with the API resources of each context.
+func queryContexts(contexts []string, q treeQuery, relations []string, opts treeOptions) []contextTrees {
+	out := make([]contextTrees, len(contexts))
+	var wg sync.WaitGroup
+	for i, context := range contexts {
+		wg.Add(1)
+		go func(i int, context string) {
+			defer wg.Done()
+			klog.V(2).Infof("[context %s] start querying trees", context)
+			src, objs, roots, err := q.run(configFlagsFor(context))
+			opts := opts
+			var single bool
+			if err == nil {
+				objs.resolveRelations(relations)
+				opts, err = opts.resolveKinds(src.all)
+				single = src.single
+			}
+			klog.V(2).Infof("[context %s] done: roots=%d error=%v", context, len(roots), err)
+			out[i] = contextTrees{context: context, objs: objs, roots: roots, single: single, opts: opts, err: err}
+		}(i, context)
+	}
+	wg.Wait()
+	return out
+}
+
+// runContexts queries the trees in each of the contexts, and prints them one after the other under the name of the
+// context, or in a single table with a CLUSTER column if combined is set. The checks are run on the trees of each
+// context.
+func runContexts(contexts []string, q treeQuery, relations []string, opts treeOptions, combined bool, checks treeChecks) error {
+	trees := queryContexts(contexts, q, relations, opts)
+	if combined {
+		combinedTreeView(color.Output, trees)
+	}
+	var failed int
+	var checkErr error
+	for i, t := range trees {
+		if !combined {
+			if i > 0 {
+				fmt.Fprintln(color.Output)
+			}
+			fmt.Fprintln(color.Output, bold.Sprintf("Cluster: %s", t.context))
+		}
+		if t.err != nil {
+			failed++
+			if combined {
+				fmt.Fprintln(color.Error, red.Sprintf("error: %s: %v", t.context, t.err))
+			} else {
+				fmt.Fprintln(color.Output, red.Sprintf("error: %v", t.err))
+			}
+			continue
+		}
+		if !combined {
+			if len(t.roots) == 0 {
+				fmt.Fprintln(color.Output, noTopLevelOwnersMessage)
+				continue
+			}
+			if err := printTrees("", t.objs, t.roots, t.single, t.opts, noOwnedResourcesMessage); err != nil {
+				return err
+			}
+		}
+		if err := checks.run("", t.objs, t.roots, t.opts.conditionTypes); err != nil {
+			checkErr = stderrors.Join(checkErr, fmt.Errorf("%s: %w", t.context, err))
+		}
+	}
+	if failed > 0 {
+		return stderrors.Join(fmt.Errorf("failed to query the trees in %d of %d contexts", failed, len(trees)), checkErr)
+	}
+	return checkErr
+}
+
+// combinedTreeView prints the trees of all contexts in a single table, with the name of the context in the first
+// CLUSTER column. The deletion previews and summaries are printed per context after the table.
+func combinedTreeView(out io.Writer, trees []contextTrees) {
+	var shown []contextTrees
+	tbl := uitable.New()
+	tbl.Separator = "  "
+	for _, t := range trees {
+		if t.err != nil || len(t.roots) == 0 {
+			continue
+		}
+		p := t
+		p.objs, p.opts = t.opts.prepare(t.objs, t.roots)
+		shown = append(shown, p)
+
+		rows := treeTable(p.objs, p.roots, p.opts).Rows
+		header, rows := rows[0], rows[1:]
+		if len(tbl.Rows) == 0 {
+			header.Cells = append([]*uitable.Cell{{Data: "CLUSTER"}}, header.Cells...)
+			tbl.Rows = append(tbl.Rows, header)
+		} else {
+			tbl.AddRow()
+		}
+		for _, r := range rows {
+			if len(r.Cells) > 0 {
+				r.Cells = append([]*uitable.Cell{{Data: t.context}}, r.Cells...)
+			}
+			tbl.Rows = append(tbl.Rows, r)
+		}
+	}
+	if len(shown) == 0 {
+		return
+	}
+	fmt.Fprintln(out, tbl)
+	if p := shown[0].opts; p.deletion == nil && !p.summary {
+		return
+	}
+	for _, p := range shown {
+		fmt.Fprintln(out)
+		fmt.Fprintln(out, bold.Sprintf("Cluster: %s", p.context))
+		if p.opts.deletion != nil {
+			p.opts.deletion.print(out, p.objs)
+		}
+		if p.opts.summary {
+			summarize(p.objs, p.roots, p.opts.conditionTypes).print(out)
+		}
+	}
+}
@@ -0,0 +1,54 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/fatih/color"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+func TestCombinedTreeView(t *testing.T) {
+	defer func(v bool) { color.NoColor = v }(color.NoColor)
+	color.NoColor = true
+
+	newTrees := func(context string, ready string) contextTrees {
+		deploy := newTestObject("apps/v1", "Deployment", "app", "d1")
+		pod := newTestObject("v1", "Pod", "app-1-a", "p1", "d1")
+		if err := unstructured.SetNestedSlice(pod.Object, []interface{}{
+			map[string]interface{}{"type": "Ready", "status": ready},
+		}, "status", "conditions"); err != nil {
+			t.Fatal(err)
+		}
+		return contextTrees{context: context, objs: newObjectDirectory([]unstructured.Unstructured{deploy, pod}), roots: []unstructured.Unstructured{deploy},
+			opts: treeOptions{conditionTypes: []string{"Ready"}, summary: true}}
+	}
+	trees := []contextTrees{
+		newTrees("staging", "True"),
+		{context: "dev", err: errors.New("connection refused")},
+		newTrees("prod", "False"),
+	}
+
+	var buf bytes.Buffer
+	combinedTreeView(&buf, trees)
+	got := buf.String()
+	for _, want := range []string{
+		"CLUSTER  NAMESPACE  NAME",
+		"staging  default    Deployment/app",
+		"staging  default    └─Pod/app-1-a   True",
+		"prod     default    └─Pod/app-1-a   False",
+		"Cluster: prod\n2 objects",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("output does not contain %q:\n%s", want, got)
+		}
+	}
+	if strings.Contains(got, "dev") {
+		t.Errorf("failed context is printed in the table:\n%s", got)
+	}
+	if n := strings.Count(got, "CLUSTER"); n != 1 {
+		t.Errorf("got %d headers, want 1:\n%s", n, got)
+	}
+}
@@ -0,0 +1,200 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+
+	"github.com/gosuri/uitable"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/utils/ptr"
+)
+
+// cascadeModes are the supported values of --cascade, which are the same as kubectl delete --cascade.
+var cascadeModes = []string{"background", "foreground", "orphan"}
+
+// deleteAction is what would happen to an object in the tree if the roots were deleted.
+type deleteAction string
+
+const (
+	// actionDelete objects are deleted, either directly or by the garbage collector.
+	actionDelete deleteAction = "delete"
+	// actionOrphan objects lose their ownerReferences to deleted owners and are left without owners.
+	actionOrphan deleteAction = "orphan"
+	// actionKeep objects lose their ownerReferences to deleted owners, but are kept by their other owners.
+	actionKeep deleteAction = "keep"
+)
+
+// deletePlan is the outcome of deleting the roots of the trees with a propagation policy, as the garbage collector
+// would carry it out. Objects that are not affected by the deletion have no action.
+type deletePlan struct {
+	propagation metav1.DeletionPropagation
+	roots       int
+	actions     map[types.UID]deleteAction
+	notes       map[types.UID][]string
+}
+
+// propagationPolicy returns the propagation policy of the --cascade mode.
+func propagationPolicy(cascade string) (metav1.DeletionPropagation, error) {
+	switch cascade {
+	case "background":
+		return metav1.DeletePropagationBackground, nil
+	case "foreground":
+		return metav1.DeletePropagationForeground, nil
+	case "orphan":
+		return metav1.DeletePropagationOrphan, nil
+	default:
+		return "", fmt.Errorf("unknown cascade mode %q (supported: %s)", cascade, strings.Join(cascadeModes, ", "))
+	}
+}
+
+// planDeletion predicts what the garbage collector would do with the objects in the trees if the roots were deleted
+// with the propagation policy. Unless dependents are orphaned, an object is deleted once all of its owners are deleted;
+// objects with owners that survive are kept. Owners that are not in objs (e.g. their kind was not queried) are
+// assumed to be gone, so that the plan errs on the side of deleting more.
+func planDeletion(objs objectDirectory, roots []unstructured.Unstructured, propagation metav1.DeletionPropagation) deletePlan {
+	p := deletePlan{
+		propagation: propagation,
+		roots:       len(roots),
+		actions:     make(map[types.UID]deleteAction),
+		notes:       make(map[types.UID][]string),
+	}
+	for _, root := range roots {
+		p.actions[root.GetUID()] = actionDelete
+	}
+	tree := treeObjects(objs, roots)
+	for changed := propagation != metav1.DeletePropagationOrphan; changed; {
+		changed = false
+		for _, obj := range tree {
+			if p.actions[obj.GetUID()] != actionDelete && p.ownersDeleted(objs, obj) {
+				p.actions[obj.GetUID()] = actionDelete
+				changed = true
+			}
+		}
+	}
+
+	for _, obj := range tree {
+		id := obj.GetUID()
+		if p.actions[id] == actionDelete {
+			if propagation == metav1.DeletePropagationForeground {
+				if n := p.blockingDependents(objs, id); n > 0 {
+					p.notes[id] = append(p.notes[id], fmt.Sprintf("waits for %d dependent(s) with blockOwnerDeletion", n))
+				}
+			}
+			if f := obj.GetFinalizers(); len(f) > 0 {
+				p.notes[id] = append(p.notes[id], "finalizers: "+strings.Join(f, ", "))
+			}
+			continue
+		}
+		var deleted, surviving []string
+		for _, ref := range obj.GetOwnerReferences() {
+			if p.actions[ref.UID] == actionDelete {
+				deleted = append(deleted, ref.Kind+"/"+ref.Name)
+			} else {
+				surviving = append(surviving, ref.Kind+"/"+ref.Name)
+			}
+		}
+		switch {
+		case len(deleted) == 0:
+			// not affected by the deletion
+		case len(surviving) > 0:
+			p.actions[id] = actionKeep
+			p.notes[id] = append(p.notes[id], "still owned by "+strings.Join(surviving, ", "))
+		default:
+			p.actions[id] = actionOrphan
+		}
+	}
+	return p
+}
+
+// ownersDeleted reports whether obj has owners and none of them survive the deletion.
+func (p deletePlan) ownersDeleted(objs objectDirectory, obj unstructured.Unstructured) bool {
+	refs := obj.GetOwnerReferences()
+	if len(refs) == 0 {
+		return false
+	}
+	for _, ref := range refs {
+		_, exists := objs.items[ref.UID]
+		if exists && objs.unresolved[ref.UID] != notFoundReason && p.actions[ref.UID] != actionDelete {
+			return false
+		}
+	}
+	return true
+}
+
+// blockingDependents returns the number of deleted dependents of the object with the specified id that have
+// blockOwnerDeletion set, which the object waits for with foreground deletion.
+func (p deletePlan) blockingDependents(objs objectDirectory, id types.UID) int {
+	var n int
+	for _, child := range objs.ownedBy(id) {
+		ref := objs.ownerRef(id, child)
+		if p.actions[child.GetUID()] == actionDelete && ref != nil && ptr.Deref(ref.BlockOwnerDeletion, false) {
+			n++
+		}
+	}
+	return n
+}
+
+// count returns the number of objects with the action.
+func (p deletePlan) count(action deleteAction) int {
+	var n int
+	for _, a := range p.actions {
+		if a == action {
+			n++
+		}
+	}
+	return n
+}
+
+// print prints the number of objects that would be deleted, orphaned and kept, in total and per kind.
+func (p deletePlan) print(out io.Writer, objs objectDirectory) {
+	fmt.Fprintf(out, "Deleting %d object(s) with %s propagation would delete %s, orphan %s and keep %s (owned by other objects).\n",
+		p.roots, p.propagation,
+		red.Sprintf("%d object(s)", p.count(actionDelete)),
+		yellow.Sprintf("%d", p.count(actionOrphan)),
+		green.Sprintf("%d", p.count(actionKeep)))
+
+	counts := make(map[string]map[deleteAction]int)
+	for id, action := range p.actions {
+		obj := objs.items[id]
+		kind := obj.GetKind()
+		if counts[kind] == nil {
+			counts[kind] = make(map[deleteAction]int)
+		}
+		counts[kind][action]++
+	}
+	var kinds []string
+	for kind := range counts {
+		kinds = append(kinds, kind)
+	}
+	slices.Sort(kinds)
+
+	tbl := uitable.New()
+	tbl.Separator = "  "
+	tbl.AddRow("KIND", "DELETE", "ORPHAN", "KEEP")
+	for _, kind := range kinds {
+		tbl.AddRow(kind, counts[kind][actionDelete], counts[kind][actionOrphan], counts[kind][actionKeep])
+	}
+	fmt.Fprintln(out)
+	fmt.Fprintln(out, tbl)
+}
+
+// actionColumns returns the ACTION and NOTE columns of an object in the tree.
+func (p deletePlan) actionColumns(id types.UID) []interface{} {
+	action := p.actions[id]
+	c := gray
+	switch action {
+	case actionDelete:
+		c = red
+	case actionOrphan:
+		c = yellow
+	case actionKeep:
+		c = green
+	default:
+		action = "-"
+	}
+	return []interface{}{c.Sprint(action), strings.Join(p.notes[id], "; ")}
+}
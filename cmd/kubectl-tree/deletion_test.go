@@ -0,0 +1,77 @@
+package main
+
+import (
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/utils/ptr"
+)
+
+func TestPlanDeletion(t *testing.T) {
+	deploy := newTestObject("apps/v1", "Deployment", "app", "d1")
+	rs := newTestObject("apps/v1", "ReplicaSet", "app-1", "rs1")
+	rs.SetOwnerReferences([]metav1.OwnerReference{{Kind: "Deployment", Name: "app", UID: "d1", BlockOwnerDeletion: ptr.To(true)}})
+	pod := newTestObject("v1", "Pod", "app-1-a", "p1", "rs1")
+	pod.SetFinalizers([]string{"example.com/cleanup"})
+	cm := newTestObject("v1", "ConfigMap", "other", "cm1")
+	shared := newTestObject("v1", "Secret", "shared", "s1")
+	shared.SetOwnerReferences([]metav1.OwnerReference{
+		{Kind: "ReplicaSet", Name: "app-1", UID: "rs1"},
+		{Kind: "ConfigMap", Name: "other", UID: "cm1"},
+	})
+	gone := newTestObject("v1", "Secret", "gone-owner", "s2")
+	gone.SetOwnerReferences([]metav1.OwnerReference{
+		{Kind: "ReplicaSet", Name: "app-1", UID: "rs1"},
+		{Kind: "ConfigMap", Name: "deleted", UID: "cm-gone"},
+	})
+	objs := newObjectDirectory([]unstructured.Unstructured{deploy, rs, pod, cm, shared, gone})
+	roots := []unstructured.Unstructured{deploy}
+
+	tests := []struct {
+		name        string
+		propagation metav1.DeletionPropagation
+		want        map[types.UID]deleteAction
+		wantNotes   map[types.UID]string
+	}{
+		{
+			name:        "background",
+			propagation: metav1.DeletePropagationBackground,
+			want:        map[types.UID]deleteAction{"d1": actionDelete, "rs1": actionDelete, "p1": actionDelete, "s1": actionKeep, "s2": actionDelete},
+			wantNotes: map[types.UID]string{
+				"p1": "finalizers: example.com/cleanup",
+				"s1": "still owned by ConfigMap/other",
+			},
+		},
+		{
+			name:        "foreground",
+			propagation: metav1.DeletePropagationForeground,
+			want:        map[types.UID]deleteAction{"d1": actionDelete, "rs1": actionDelete, "p1": actionDelete, "s1": actionKeep, "s2": actionDelete},
+			wantNotes: map[types.UID]string{
+				"d1": "waits for 1 dependent(s) with blockOwnerDeletion",
+				"p1": "finalizers: example.com/cleanup",
+				"s1": "still owned by ConfigMap/other",
+			},
+		},
+		{
+			name:        "orphan",
+			propagation: metav1.DeletePropagationOrphan,
+			want:        map[types.UID]deleteAction{"d1": actionDelete, "rs1": actionOrphan},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := planDeletion(objs, roots, tt.propagation)
+			for _, obj := range objs.items {
+				id := obj.GetUID()
+				if got := p.actions[id]; got != tt.want[id] {
+					t.Errorf("action of %s = %q, want %q", id, got, tt.want[id])
+				}
+				if got := p.actionColumns(id)[1]; got != tt.wantNotes[id] {
+					t.Errorf("note of %s = %q, want %q", id, got, tt.wantNotes[id])
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,352 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/fatih/color"
+	"github.com/gosuri/uitable"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/yaml"
+)
+
+const (
+	diffFromFlag = "from"
+	diffToFlag   = "to"
+
+	// contextSourcePrefix marks a diff source that is a kubeconfig context, rather than a file.
+	contextSourcePrefix = "context:"
+)
+
+var diffCmd = &cobra.Command{
+	Use:          "diff [KIND NAME...] --from SOURCE [--to SOURCE]",
+	SilenceUsage: true,
+	Short:        "Compare the trees of objects between clusters, or with a saved snapshot",
+	Long: "Compare the trees of objects in two sources, and print the objects that were added, removed or changed their\n" +
+		"READY or STATUS in tree form. A source is either a file written with 'kubectl tree -o json' (or -o yaml),\n" +
+		"a snapshot saved with --save-snapshot, or context:NAME for the live trees in a kubeconfig context. When --to\n" +
+		"is not set, the live trees in the current context are used. Without KIND arguments, the live trees of the roots\n" +
+		"in the other source are compared.\n\n" +
+		"Objects are matched by their kind, namespace and name, under the matching parent.",
+	Example: "  kubectl tree deploy my-app -o json > before.json\n" +
+		"  kubectl tree diff deploy my-app --from before.json\n" +
+		"  kubectl tree diff deploy my-app --from context:staging --to context:prod\n" +
+		"  kubectl tree diff --from before.json --to after.json\n" +
+		"  kubectl tree deploy my-app --save-snapshot before.snapshot\n" +
+		"  kubectl tree diff --from before.snapshot",
+	Args: cobra.ArbitraryArgs,
+	RunE: runDiff,
+}
+
+// diffChange is how an object differs between the sources.
+type diffChange int
+
+const (
+	unchanged diffChange = iota
+	added
+	removed
+	changed
+)
+
+// treeDiff is an object in either of the compared trees, with the differences of its descendants.
+type treeDiff struct {
+	node     treeNode
+	change   diffChange
+	details  []string
+	children []treeDiff
+}
+
+func runDiff(command *cobra.Command, args []string) error {
+	if err := setColor(command); err != nil {
+		return err
+	}
+	from, err := command.Flags().GetString(diffFromFlag)
+	if err != nil {
+		return err
+	}
+	if from == "" {
+		return errors.Errorf("--%s is required", diffFromFlag)
+	}
+	to, err := command.Flags().GetString(diffToFlag)
+	if err != nil {
+		return err
+	}
+	if len(args) == 0 && isLiveSource(from) && isLiveSource(to) {
+		return errors.New("requires a KIND argument to compare the live trees")
+	}
+	if command.Flags().Changed(fromFileFlag) {
+		return errors.Errorf("--%s cannot be used with diff, use --%s or --%s with a file written with -o json or a snapshot", fromFileFlag, diffFromFlag, diffToFlag)
+	}
+	conditionTypes, err := command.Flags().GetStringSlice(conditionTypesFlag)
+	if err != nil {
+		return err
+	}
+
+	var q treeQuery
+	q.args = args
+	if q.allNs, err = command.Flags().GetBool(allNamespacesFlag); err != nil {
+		q.allNs = false
+	}
+	if q.labelSelector, err = command.Flags().GetString(selectorFlag); err != nil {
+		return err
+	}
+	if q.apiGroups, err = command.Flags().GetStringSlice(apiGroupsFlag); err != nil {
+		return err
+	}
+	if q.resources, err = command.Flags().GetStringSlice(resourcesFlag); err != nil {
+		return err
+	}
+	if q.refresh, err = command.Flags().GetBool(refreshDiscoveryFlag); err != nil {
+		return err
+	}
+	if q.cacheTTL, err = command.Flags().GetDuration(discoveryCacheTTLFlag); err != nil {
+		return err
+	}
+
+	// without KIND arguments, the roots of the saved trees are queried from the live source, so the saved source is
+	// loaded first
+	var oldTrees, newTrees []treeNode
+	var fromName, toName string
+	if !isLiveSource(from) {
+		if oldTrees, fromName, err = loadDiffSource(from, q, conditionTypes); err != nil {
+			return fmt.Errorf("failed to load --%s: %w", diffFromFlag, err)
+		}
+		if len(args) == 0 {
+			q.rootRefs = diffRootRefs(oldTrees)
+		}
+	}
+	if newTrees, toName, err = loadDiffSource(to, q, conditionTypes); err != nil {
+		return fmt.Errorf("failed to load --%s: %w", diffToFlag, err)
+	}
+	if isLiveSource(from) {
+		if len(args) == 0 {
+			q.rootRefs = diffRootRefs(newTrees)
+		}
+		if oldTrees, fromName, err = loadDiffSource(from, q, conditionTypes); err != nil {
+			return fmt.Errorf("failed to load --%s: %w", diffFromFlag, err)
+		}
+	}
+	printDiff(color.Output, fromName, toName, diffTrees(oldTrees, newTrees))
+	return nil
+}
+
+// diffRootRefs returns the root objects of the trees with their kind, namespace and name, to query the same roots from
+// a live source.
+func diffRootRefs(nodes []treeNode) []unstructured.Unstructured {
+	var out []unstructured.Unstructured
+	for _, n := range nodes {
+		var obj unstructured.Unstructured
+		obj.SetAPIVersion(n.APIVersion)
+		obj.SetKind(n.Kind)
+		obj.SetNamespace(n.Namespace)
+		obj.SetName(n.Name)
+		out = append(out, obj)
+	}
+	return out
+}
+
+func isLiveSource(source string) bool {
+	return source == "" || strings.HasPrefix(source, contextSourcePrefix)
+}
+
+// loadDiffSource returns the trees of a source and its name: a file written with -o json or -o yaml or a snapshot,
+// context:NAME for the live trees in a kubeconfig context, or "" for the live trees in the current context. The READY
+// of the objects in snapshots and live trees is determined by the condition types.
+func loadDiffSource(source string, q treeQuery, conditionTypes []string) ([]treeNode, string, error) {
+	if !isLiveSource(source) {
+		nodes, err := readTreeNodes(source, conditionTypes)
+		return nodes, source, err
+	}
+	f := cf
+	if context := strings.TrimPrefix(source, contextSourcePrefix); context != "" {
+		f = configFlagsFor(context)
+	}
+	name := "context " + contextName(f)
+	_, objs, roots, err := q.run(f)
+	if err != nil {
+		return nil, name, err
+	}
+	return diffNodes(objs, roots, conditionTypes), name, nil
+}
+
+func diffNodes(objs objectDirectory, roots []unstructured.Unstructured, conditionTypes []string) []treeNode {
+	var nodes []treeNode
+	visits := make(map[types.UID]visitState)
+	for _, root := range roots {
+		nodes = append(nodes, buildTreeNode(objs, root, 0, treeOptions{conditionTypes: conditionTypes}, visits))
+	}
+	return nodes
+}
+
+// readTreeNodes reads the trees from a snapshot, or from a file written with -o json or -o yaml, which has either a
+// single tree or a list.
+func readTreeNodes(path string, conditionTypes []string) ([]treeNode, error) {
+	if isSnapshot(path) {
+		s, err := readSnapshot(path)
+		if err != nil {
+			return nil, err
+		}
+		objs := newObjectDirectory(s.objects())
+		return diffNodes(objs, latestRoots(objs, s.roots()), conditionTypes), nil
+	}
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var nodes []treeNode
+	if err := yaml.Unmarshal(b, &nodes); err == nil {
+		return nodes, nil
+	}
+	var node treeNode
+	if err := yaml.Unmarshal(b, &node); err != nil {
+		return nil, fmt.Errorf("failed to decode the trees in %s: %w", path, err)
+	}
+	return []treeNode{node}, nil
+}
+
+// diffKey identifies an object among its siblings in the trees, regardless of its API version and UID, which differ
+// between clusters.
+func diffKey(n treeNode) string {
+	gk := schema.FromAPIVersionAndKind(n.APIVersion, n.Kind).GroupKind()
+	return strings.Join([]string{n.Relation, gk.String(), n.Namespace, n.Name}, "/")
+}
+
+// diffTrees matches the objects of the old and new trees with the same key under the matching parents, and returns
+// the differences in the order of the new trees, followed by the removed objects.
+func diffTrees(oldNodes, newNodes []treeNode) []treeDiff {
+	var out []treeDiff
+	matched := make([]bool, len(oldNodes))
+	for _, n := range newNodes {
+		i := -1
+		for j, o := range oldNodes {
+			if !matched[j] && diffKey(o) == diffKey(n) {
+				i = j
+				break
+			}
+		}
+		if i < 0 {
+			out = append(out, treeDiff{node: n, change: added, children: diffTrees(nil, n.Children)})
+			continue
+		}
+		matched[i] = true
+		o := oldNodes[i]
+		d := treeDiff{node: n, children: diffTrees(o.Children, n.Children)}
+		if o.Ready != n.Ready {
+			d.details = append(d.details, fmt.Sprintf("READY %s → %s", orDash(string(o.Ready)), orDash(string(n.Ready))))
+		}
+		if o.Status != n.Status {
+			d.details = append(d.details, fmt.Sprintf("STATUS %s → %s", orDash(string(o.Status)), orDash(string(n.Status))))
+		}
+		if len(d.details) > 0 {
+			d.change = changed
+		}
+		out = append(out, d)
+	}
+	for i, o := range oldNodes {
+		if !matched[i] {
+			out = append(out, treeDiff{node: o, change: removed, children: diffTrees(o.Children, nil)})
+		}
+	}
+	return out
+}
+
+// hasChanges reports whether the object or any of its descendants changed.
+func (d treeDiff) hasChanges() bool {
+	if d.change != unchanged {
+		return true
+	}
+	for _, c := range d.children {
+		if c.hasChanges() {
+			return true
+		}
+	}
+	return false
+}
+
+// count returns the number of objects with the change in d and its descendants.
+func (d treeDiff) count(change diffChange) int {
+	var n int
+	if d.change == change {
+		n++
+	}
+	for _, c := range d.children {
+		n += c.count(change)
+	}
+	return n
+}
+
+// printDiff prints the changed objects in tree form, along with their unchanged ancestors.
+func printDiff(out io.Writer, fromName, toName string, diffs []treeDiff) {
+	fmt.Fprintln(out, red.Sprint("--- "+fromName))
+	fmt.Fprintln(out, green.Sprint("+++ "+toName))
+	fmt.Fprintln(out)
+
+	var counts [changed + 1]int
+	tbl := uitable.New()
+	tbl.Separator = "  "
+	tbl.AddRow("", "NAMESPACE", "NAME", "CHANGE")
+	for _, d := range diffs {
+		if !d.hasChanges() {
+			continue
+		}
+		if len(tbl.Rows) > 1 {
+			tbl.AddRow()
+		}
+		diffViewInner("", tbl, d)
+		for c := range counts {
+			counts[c] += d.count(diffChange(c))
+		}
+	}
+	if len(tbl.Rows) == 1 {
+		fmt.Fprintln(out, "No differences found.")
+		return
+	}
+	fmt.Fprintln(out, tbl)
+	fmt.Fprintf(out, "\n%d added, %d removed, %d changed\n", counts[added], counts[removed], counts[changed])
+}
+
+func diffViewInner(prefix string, tbl *uitable.Table, d treeDiff) {
+	marker, c, change := " ", gray, ""
+	switch d.change {
+	case added:
+		marker, c, change = "+", green, "added"
+	case removed:
+		marker, c, change = "-", red, "removed"
+	case changed:
+		marker, c, change = "~", yellow, strings.Join(d.details, ", ")
+	}
+	var rel string
+	if d.node.Relation != "" {
+		rel = fmt.Sprintf("[%s] ", d.node.Relation)
+	}
+	tbl.AddRow(c.Sprint(marker), d.node.Namespace,
+		gray.Sprint(printPrefix(prefix))+c.Sprintf("%s%s/%s", rel, d.node.Kind, d.node.Name),
+		c.Sprint(change))
+
+	var chs []treeDiff
+	for _, child := range d.children {
+		if child.hasChanges() {
+			chs = append(chs, child)
+		}
+	}
+	for i, child := range chs {
+		p := prefix + firstElemPrefix
+		if i == len(chs)-1 {
+			p = prefix + lastElemPrefix
+		}
+		diffViewInner(p, tbl, child)
+	}
+}
+
+func init() {
+	diffCmd.Flags().String(diffFromFlag, "", "The source of the old trees: a file written with -o json or -o yaml, a snapshot saved with --save-snapshot, or context:NAME for the live trees in a kubeconfig context")
+	diffCmd.Flags().StringSlice(conditionTypesFlag, []string{"Ready"}, "Comma-separated list of condition types that determine the READY of the objects in the live trees and snapshots, like in the trees (files written with -o json or -o yaml have it already)")
+	diffCmd.Flags().String(diffToFlag, "", "The source of the new trees, like --from (default: the live trees in the current context)")
+	rootCmd.AddCommand(diffCmd)
+}
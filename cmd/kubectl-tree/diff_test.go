@@ -0,0 +1,144 @@
+package main
+
+import (
+	"bytes"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/fatih/color"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+func TestDiffTrees(t *testing.T) {
+	defer func(v bool) { color.NoColor = v }(color.NoColor)
+	color.NoColor = true
+
+	oldTrees := []treeNode{{APIVersion: "apps/v1", Kind: "Deployment", Namespace: "default", Name: "app", Children: []treeNode{
+		{APIVersion: "apps/v1", Kind: "ReplicaSet", Namespace: "default", Name: "app-1", Children: []treeNode{
+			{APIVersion: "v1", Kind: "Pod", Namespace: "default", Name: "app-1-a", Ready: "True", Status: "Current"},
+			{APIVersion: "v1", Kind: "Pod", Namespace: "default", Name: "app-1-b", Ready: "True", Status: "Current"},
+		}},
+		{APIVersion: "v1", Kind: "ConfigMap", Namespace: "default", Name: "app-config"},
+	}}}
+	newTrees := []treeNode{{APIVersion: "apps/v1", Kind: "Deployment", Namespace: "default", Name: "app", Children: []treeNode{
+		{APIVersion: "apps/v1", Kind: "ReplicaSet", Namespace: "default", Name: "app-1", Children: []treeNode{
+			{APIVersion: "v1", Kind: "Pod", Namespace: "default", Name: "app-1-a", Ready: "False", Status: "InProgress"},
+			{APIVersion: "v1", Kind: "Pod", Namespace: "default", Name: "app-1-c", Ready: "True", Status: "Current"},
+		}},
+		{APIVersion: "v1beta1", Kind: "ConfigMap", Namespace: "default", Name: "app-config"},
+	}}}
+
+	diffs := diffTrees(oldTrees, newTrees)
+	if len(diffs) != 1 || diffs[0].count(added) != 1 || diffs[0].count(removed) != 1 || diffs[0].count(changed) != 1 {
+		t.Fatalf("unexpected diff: %+v", diffs)
+	}
+
+	var buf bytes.Buffer
+	printDiff(&buf, "before.json", "context prod", diffs)
+	got := buf.String()
+	for _, want := range []string{
+		"--- before.json",
+		"+++ context prod",
+		"~  default      ├─Pod/app-1-a     READY True → False, STATUS Current → InProgress",
+		"+  default      ├─Pod/app-1-c     added",
+		"-  default      └─Pod/app-1-b     removed",
+		"1 added, 1 removed, 1 changed",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("output does not contain %q:\n%s", want, got)
+		}
+	}
+	if strings.Contains(got, "app-config") {
+		t.Errorf("unchanged objects are printed:\n%s", got)
+	}
+
+	buf.Reset()
+	printDiff(&buf, "a", "b", diffTrees(oldTrees, oldTrees))
+	if !strings.Contains(buf.String(), "No differences found.") {
+		t.Errorf("unexpected output for identical trees:\n%s", buf.String())
+	}
+}
+
+func TestReadTreeNodesConditionTypes(t *testing.T) {
+	deploy := newTestObject("apps/v1", "Deployment", "app", "d1")
+	pod := newTestObject("v1", "Pod", "app-1-a", "p1", "d1")
+	if err := unstructured.SetNestedSlice(pod.Object, []interface{}{
+		map[string]interface{}{"type": "Ready", "status": "True"},
+		map[string]interface{}{"type": "Processed", "status": "False"},
+	}, "status", "conditions"); err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(t.TempDir(), "app.snapshot")
+	if err := writeSnapshot(path, newSnapshot("prod", []unstructured.Unstructured{deploy}, true, []unstructured.Unstructured{deploy, pod})); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tt := range []struct {
+		conditionTypes []string
+		want           ReadyStatus
+	}{
+		{[]string{"Ready"}, "True"},
+		{[]string{"Processed"}, "False"},
+	} {
+		nodes, err := readTreeNodes(path, tt.conditionTypes)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(nodes) != 1 || len(nodes[0].Children) != 1 {
+			t.Fatalf("unexpected trees: %+v", nodes)
+		}
+		if got := nodes[0].Children[0].Ready; got != tt.want {
+			t.Errorf("--condition-types=%v: got READY %q, want %q", tt.conditionTypes, got, tt.want)
+		}
+	}
+}
+
+func TestDiffFromSnapshot(t *testing.T) {
+	defer func(v bool) { color.NoColor = v }(color.NoColor)
+	color.NoColor = true
+
+	withReady := func(obj unstructured.Unstructured, ready string) unstructured.Unstructured {
+		if err := unstructured.SetNestedSlice(obj.Object, []interface{}{
+			map[string]interface{}{"type": "Ready", "status": ready},
+		}, "status", "conditions"); err != nil {
+			t.Fatal(err)
+		}
+		return obj
+	}
+	deploy := newTestObject("apps/v1", "Deployment", "app", "d1")
+	saved := []unstructured.Unstructured{
+		deploy,
+		newTestObject("apps/v1", "ReplicaSet", "app-1", "rs1", "d1"),
+		withReady(newTestObject("v1", "Pod", "app-1-a", "p1", "rs1"), "True"),
+	}
+	snapshot := filepath.Join(t.TempDir(), "before.snapshot")
+	if err := writeSnapshot(snapshot, newSnapshot("test", []unstructured.Unstructured{deploy}, true, saved)); err != nil {
+		t.Fatal(err)
+	}
+	// the live objects are recreated with different UIDs, and the unrelated Deployment is not compared
+	kubeconfig := newFakeAPIServer(t,
+		newTestObject("apps/v1", "Deployment", "app", "live-d1"),
+		newTestObject("apps/v1", "ReplicaSet", "app-1", "live-rs1", "live-d1"),
+		withReady(newTestObject("v1", "Pod", "app-1-a", "live-p1", "live-rs1"), "False"),
+		withReady(newTestObject("v1", "Pod", "app-1-b", "live-p2", "live-rs1"), "True"),
+		newTestObject("apps/v1", "Deployment", "other", "live-d2"),
+		newTestObject("apps/v1", "ReplicaSet", "other-1", "live-rs2", "live-d2"),
+	)
+
+	got := runCommand(t, kubeconfig, "diff", "--from", snapshot)
+	for _, want := range []string{
+		"--- " + snapshot,
+		"+++ context test",
+		"~  default      ├─Pod/app-1-a     READY True → False",
+		"+  default      └─Pod/app-1-b     added",
+		"1 added, 0 removed, 1 changed",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("output does not contain %q:\n%s", want, got)
+		}
+	}
+	if strings.Contains(got, "other") {
+		t.Errorf("trees of other roots are compared:\n%s", got)
+	}
+}
@@ -0,0 +1,51 @@
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+	"k8s.io/client-go/discovery"
+	"k8s.io/client-go/discovery/cached/disk"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/util/homedir"
+	"k8s.io/klog"
+	"k8s.io/utils/ptr"
+)
+
+// defaultDiscoveryCacheTTL is how long the cached API discovery results are used before querying the server again.
+const defaultDiscoveryCacheTTL = 6 * time.Hour
+
+var unsafeFileCharacters = regexp.MustCompile(`[^\w.-]`)
+
+// newCachedDiscoveryClient returns a discovery client that caches the API discovery results on disk under the
+// --cache-dir directory, in a subdirectory specific to the kubeconfig context and the API server.
+func newCachedDiscoveryClient(f *genericclioptions.ConfigFlags, restConfig *rest.Config, ttl time.Duration) (discovery.CachedDiscoveryInterface, error) {
+	cacheDir := ptr.Deref(f.CacheDir, "")
+	if cacheDir == "" {
+		cacheDir = filepath.Join(homedir.HomeDir(), ".kube", "cache")
+	}
+	discoveryDir := filepath.Join(cacheDir, "kubectl-tree", "discovery", discoveryCacheKey(f, restConfig.Host))
+	klog.V(3).Infof("using discovery cache at %s (ttl=%v)", discoveryDir, ttl)
+	dc, err := disk.NewCachedDiscoveryClientForConfig(restConfig, discoveryDir, filepath.Join(cacheDir, "http"), ttl)
+	if err != nil {
+		return nil, fmt.Errorf("failed to construct cached discovery client: %w", err)
+	}
+	return dc, nil
+}
+
+// discoveryCacheKey returns a directory name for the current kubeconfig context and the API server host, so that
+// contexts with the same name in different kubeconfig files don't share the cache.
+func discoveryCacheKey(f *genericclioptions.ConfigFlags, host string) string {
+	context := ptr.Deref(f.Context, "")
+	if context == "" {
+		if raw, err := f.ToRawKubeConfigLoader().RawConfig(); err == nil {
+			context = raw.CurrentContext
+		}
+	}
+	host = strings.TrimPrefix(strings.TrimPrefix(host, "https://"), "http://")
+	return unsafeFileCharacters.ReplaceAllString(context+"_"+host, "_")
+}
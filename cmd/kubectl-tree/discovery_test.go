@@ -0,0 +1,105 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+	"k8s.io/client-go/rest"
+)
+
+func TestDiscoveryCacheKey(t *testing.T) {
+	kubeconfig := filepath.Join(t.TempDir(), "config")
+	if err := os.WriteFile(kubeconfig, []byte("apiVersion: v1\nkind: Config\ncurrent-context: kind-dev\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name    string
+		context string
+		host    string
+		want    string
+	}{
+		{name: "context flag", context: "prod", host: "https://10.0.0.1:6443", want: "prod_10.0.0.1_6443"},
+		{name: "http host", context: "prod", host: "http://localhost:8080", want: "prod_localhost_8080"},
+		{name: "unsafe characters", context: "arn:aws:eks:us-west-2:1:cluster/prod", host: "https://example.com/k8s",
+			want: "arn_aws_eks_us-west-2_1_cluster_prod_example.com_k8s"},
+		{name: "current context", host: "https://10.0.0.1:6443", want: "kind-dev_10.0.0.1_6443"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := genericclioptions.NewConfigFlags(false)
+			f.KubeConfig = &kubeconfig
+			f.Context = &tt.context
+			if got := discoveryCacheKey(f, tt.host); got != tt.want {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCachedDiscoveryClient(t *testing.T) {
+	var requests atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body interface{}
+		switch r.URL.Path {
+		case "/api":
+			requests.Add(1)
+			body = metav1.APIVersions{Versions: []string{"v1"}}
+		case "/apis":
+			body = metav1.APIGroupList{}
+		default:
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(body)
+	}))
+	defer srv.Close()
+
+	cacheDir, context := t.TempDir(), "test"
+	f := genericclioptions.NewConfigFlags(false)
+	f.CacheDir, f.Context = &cacheDir, &context
+	// each call is a separate kubectl-tree invocation, with a new client reading the cache from disk
+	discover := func(ttl time.Duration, refresh bool) {
+		t.Helper()
+		dc, err := newCachedDiscoveryClient(f, &rest.Config{Host: srv.URL}, ttl)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if refresh {
+			dc.Invalidate()
+		}
+		if _, err := dc.ServerGroups(); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	discover(time.Hour, false)
+	if n := requests.Load(); n != 1 {
+		t.Fatalf("got %d discovery requests, want 1", n)
+	}
+	path := filepath.Join(cacheDir, "kubectl-tree", "discovery", discoveryCacheKey(f, srv.URL), "servergroups.json")
+	if _, err := os.Stat(path); err != nil {
+		t.Fatalf("discovery results are not cached: %v", err)
+	}
+
+	discover(time.Hour, false)
+	if n := requests.Load(); n != 1 {
+		t.Errorf("got %d discovery requests with a cache within its TTL, want 1", n)
+	}
+	discover(0, false)
+	if n := requests.Load(); n != 2 {
+		t.Errorf("got %d discovery requests with TTL=0, want 2", n)
+	}
+	discover(time.Hour, true)
+	if n := requests.Load(); n != 3 {
+		t.Errorf("got %d discovery requests after --%s, want 3", n, refreshDiscoveryFlag)
+	}
+}
@@ -0,0 +1,168 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/duration"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/klog"
+)
+
+const (
+	// maxEventsPerObject is the number of most recent events shown under each object.
+	maxEventsPerObject = 3
+
+	// maxEventMessageLength is where event messages are truncated, so that they don't widen the table too much.
+	maxEventMessageLength = 80
+
+	defaultEventsSince = time.Hour
+)
+
+var (
+	eventsGVR       = schema.GroupVersionResource{Group: "events.k8s.io", Version: "v1", Resource: "events"}
+	legacyEventsGVR = schema.GroupVersionResource{Version: "v1", Resource: "events"}
+)
+
+// objectEvent is a warning event about an object.
+type objectEvent struct {
+	Reason  string    `json:"reason"`
+	Message string    `json:"message"`
+	Count   int64     `json:"count,omitempty"`
+	Time    time.Time `json:"time"`
+}
+
+// eventIndex holds the most recent warning events by the UID of the object they are about.
+type eventIndex map[types.UID][]objectEvent
+
+// getEvents lists the events.k8s.io/v1 Events, or the core v1 Events on clusters where the former is not served or
+// not allowed, in the namespace or in all namespaces if it's empty.
+func getEvents(client dynamic.Interface, namespace string) ([]unstructured.Unstructured, error) {
+	var out []unstructured.Unstructured
+	for _, gvr := range []schema.GroupVersionResource{eventsGVR, legacyEventsGVR} {
+		var ri dynamic.ResourceInterface = client.Resource(gvr)
+		if namespace != "" {
+			ri = client.Resource(gvr).Namespace(namespace)
+		}
+		var next string
+		for {
+			resp, err := ri.List(context.TODO(), metav1.ListOptions{Limit: 500, Continue: next})
+			if apierrors.IsNotFound(err) && gvr == eventsGVR {
+				klog.V(2).Infof("%s is not served, falling back to %s", eventsGVR, legacyEventsGVR)
+				break
+			}
+			if apierrors.IsForbidden(err) && gvr == eventsGVR {
+				// RBAC rules often only grant access to the core Events
+				klog.V(2).Infof("cannot query %s (forbidden), falling back to %s", eventsGVR, legacyEventsGVR)
+				break
+			}
+			if apierrors.IsForbidden(err) {
+				klog.Infof("cannot query %s (forbidden), omitting events", gvr.GroupResource())
+				return nil, nil
+			}
+			if err != nil {
+				return nil, fmt.Errorf("failed to list events: %w", err)
+			}
+			out = append(out, resp.Items...)
+			if next = resp.GetContinue(); next == "" {
+				klog.V(2).Infof("found %d events in %s", len(out), gvr)
+				return out, nil
+			}
+		}
+	}
+	return out, nil
+}
+
+// indexEvents indexes the warning events among objs (either events.k8s.io/v1 or core v1 Events) that occurred within
+// since (or at any time if since is 0) by the object they are about. The events are sorted most recent first.
+func indexEvents(objs []unstructured.Unstructured, since time.Duration) eventIndex {
+	idx := make(eventIndex)
+	seen := make(map[types.UID]bool)
+	for _, obj := range objs {
+		if obj.GetKind() != "Event" || (obj.GroupVersionKind().Group != eventsGVR.Group && obj.GroupVersionKind().Group != "") {
+			continue
+		}
+		// the same event is served by both APIs
+		if seen[obj.GetUID()] {
+			continue
+		}
+		seen[obj.GetUID()] = true
+
+		if nestedString(obj.Object, "type") != "Warning" {
+			continue
+		}
+		var uid types.UID
+		var ev objectEvent
+		if obj.GroupVersionKind().Group == eventsGVR.Group {
+			uid = types.UID(nestedString(obj.Object, "regarding", "uid"))
+			ev.Message = nestedString(obj.Object, "note")
+			ev.Count = nestedInt64(obj.Object, "series", "count")
+			if ev.Count == 0 {
+				ev.Count = nestedInt64(obj.Object, "deprecatedCount")
+			}
+			ev.Time = latestTime(obj.Object, []string{"series", "lastObservedTime"}, []string{"eventTime"},
+				[]string{"deprecatedLastTimestamp"})
+		} else {
+			uid = types.UID(nestedString(obj.Object, "involvedObject", "uid"))
+			ev.Message = nestedString(obj.Object, "message")
+			ev.Count = nestedInt64(obj.Object, "count")
+			if c := nestedInt64(obj.Object, "series", "count"); c > ev.Count {
+				ev.Count = c
+			}
+			ev.Time = latestTime(obj.Object, []string{"series", "lastObservedTime"}, []string{"lastTimestamp"},
+				[]string{"eventTime"}, []string{"firstTimestamp"})
+		}
+		ev.Reason = nestedString(obj.Object, "reason")
+		if uid == "" || (since > 0 && time.Since(ev.Time) > since) {
+			continue
+		}
+		idx[uid] = append(idx[uid], ev)
+	}
+	for uid, evs := range idx {
+		slices.SortFunc(evs, func(a, b objectEvent) int { return b.Time.Compare(a.Time) })
+		if len(evs) > maxEventsPerObject {
+			idx[uid] = evs[:maxEventsPerObject]
+		}
+	}
+	return idx
+}
+
+// latestTime returns the most recent of the timestamps at the specified fields of obj.
+func latestTime(obj map[string]interface{}, fields ...[]string) time.Time {
+	var out time.Time
+	for _, f := range fields {
+		t, err := time.Parse(time.RFC3339, nestedString(obj, f...))
+		if err == nil && t.After(out) {
+			out = t
+		}
+	}
+	return out
+}
+
+// String describes the event in a single line, e.g. "BackOff (x12, 3m ago): Back-off restarting failed container".
+func (e objectEvent) String() string {
+	var details []string
+	if e.Count > 1 {
+		details = append(details, fmt.Sprintf("x%d", e.Count))
+	}
+	if !e.Time.IsZero() {
+		details = append(details, duration.HumanDuration(time.Since(e.Time))+" ago")
+	}
+	s := e.Reason
+	if len(details) > 0 {
+		s += " (" + strings.Join(details, ", ") + ")"
+	}
+	msg := strings.Join(strings.Fields(e.Message), " ")
+	if r := []rune(msg); len(r) > maxEventMessageLength {
+		msg = string(r[:maxEventMessageLength-1]) + "…"
+	}
+	return s + ": " + msg
+}
@@ -0,0 +1,106 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+	k8stesting "k8s.io/client-go/testing"
+)
+
+func newTestEvent(apiVersion string, uid types.UID, eventType, reason, regarding string, at time.Time) unstructured.Unstructured {
+	ev := newTestObject(apiVersion, "Event", string(uid), uid)
+	ev.Object["type"] = eventType
+	ev.Object["reason"] = reason
+	if apiVersion == "v1" {
+		ev.Object["involvedObject"] = map[string]interface{}{"uid": regarding}
+		ev.Object["message"] = reason + " message"
+		ev.Object["lastTimestamp"] = at.UTC().Format(time.RFC3339)
+	} else {
+		ev.Object["regarding"] = map[string]interface{}{"uid": regarding}
+		ev.Object["note"] = reason + " note"
+		ev.Object["eventTime"] = at.UTC().Format(time.RFC3339)
+	}
+	return ev
+}
+
+func TestIndexEvents(t *testing.T) {
+	now := time.Now()
+	objs := []unstructured.Unstructured{
+		newTestEvent("events.k8s.io/v1", "e1", "Warning", "BackOff", "p1", now.Add(-time.Minute)),
+		newTestEvent("v1", "e1", "Warning", "BackOff", "p1", now.Add(-time.Minute)), // same event from the core API
+		newTestEvent("v1", "e2", "Warning", "Unhealthy", "p1", now.Add(-10*time.Second)),
+		newTestEvent("v1", "e3", "Normal", "Pulled", "p1", now),
+		newTestEvent("v1", "e4", "Warning", "FailedMount", "p1", now.Add(-2*time.Hour)),
+		newTestEvent("events.k8s.io/v1", "e5", "Warning", "FailedCreate", "rs1", now),
+		newTestObject("v1", "Pod", "app", "p1"),
+	}
+
+	tests := []struct {
+		name  string
+		since time.Duration
+		uid   types.UID
+		want  []string
+	}{
+		{name: "recent warnings, most recent first", since: time.Hour, uid: "p1", want: []string{"Unhealthy", "BackOff"}},
+		{name: "no time limit", since: 0, uid: "p1", want: []string{"Unhealthy", "BackOff", "FailedMount"}},
+		{name: "events.k8s.io", since: time.Hour, uid: "rs1", want: []string{"FailedCreate"}},
+		{name: "no events", since: time.Hour, uid: "d1", want: nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := indexEvents(objs, tt.since)[tt.uid]
+			if len(got) != len(tt.want) {
+				t.Fatalf("got %d events (%v), want %v", len(got), got, tt.want)
+			}
+			for i, ev := range got {
+				if ev.Reason != tt.want[i] {
+					t.Fatalf("got events %v, want %v", got, tt.want)
+				}
+			}
+		})
+	}
+}
+
+func TestGetEventsForbidden(t *testing.T) {
+	ev := newTestEvent("v1", "e1", "Warning", "BackOff", "p1", time.Now())
+	newClient := func() *dynamicfake.FakeDynamicClient {
+		return dynamicfake.NewSimpleDynamicClientWithCustomListKinds(runtime.NewScheme(), map[schema.GroupVersionResource]string{
+			eventsGVR:       "EventList",
+			legacyEventsGVR: "EventList",
+		}, &ev)
+	}
+	forbid := func(gvr schema.GroupVersionResource) k8stesting.ReactionFunc {
+		return func(action k8stesting.Action) (bool, runtime.Object, error) {
+			if action.GetResource() != gvr {
+				return false, nil, nil
+			}
+			return true, nil, apierrors.NewForbidden(gvr.GroupResource(), "", nil)
+		}
+	}
+
+	// only the core Events are allowed
+	client := newClient()
+	client.PrependReactor("list", "events", forbid(eventsGVR))
+	evs, err := getEvents(client, "default")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(evs) != 1 || evs[0].GetUID() != "e1" {
+		t.Errorf("expected the core Events after events.k8s.io is forbidden, got %v", evs)
+	}
+
+	// neither is allowed, the events are omitted
+	client = newClient()
+	client.PrependReactor("list", "events", forbid(eventsGVR))
+	client.PrependReactor("list", "events", forbid(legacyEventsGVR))
+	evs, err = getEvents(client, "default")
+	if err != nil || evs != nil {
+		t.Errorf("expected no events and no error when both are forbidden, got %v, %v", evs, err)
+	}
+}
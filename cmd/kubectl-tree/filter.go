@@ -0,0 +1,118 @@
+package main
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+// treeFilter selects the objects shown in the tree after querying. Unlike --resources, which excludes objects from
+// the query (and therefore breaks the chain under excluded owners), the ancestors of the matching objects are kept.
+type treeFilter struct {
+	// kinds are the resource types (e.g. pods or deploy) of the objects to show.
+	kinds kindSelector
+
+	// statuses are the kstatus results (e.g. failed or inprogress, case-insensitive) of the objects to show.
+	statuses []string
+
+	// notReady shows the objects whose READY condition is False or Unknown.
+	notReady bool
+}
+
+// statusFilters are the supported values of --only-status.
+func statusFilters() []string {
+	var out []string
+	for _, s := range summaryStatuses {
+		if s != "" {
+			out = append(out, strings.ToLower(string(s)))
+		}
+	}
+	return out
+}
+
+// validateStatusFilters returns an error if any of the --only-status values is not supported.
+func validateStatusFilters(statuses []string) error {
+	for _, s := range statuses {
+		if !slices.Contains(statusFilters(), strings.ToLower(s)) {
+			return fmt.Errorf("unknown status %q (supported: %s)", s, strings.Join(statusFilters(), ", "))
+		}
+	}
+	return nil
+}
+
+func (f treeFilter) enabled() bool {
+	return !f.kinds.empty() || len(f.statuses) > 0 || f.notReady
+}
+
+// matches reports whether obj matches all of the criteria of the filter.
+func (f treeFilter) matches(objs objectDirectory, obj unstructured.Unstructured, conditionTypes []string) bool {
+	if !f.kinds.empty() && !f.kinds.matches(obj) {
+		return false
+	}
+	ready, _, kstatus := objs.statusOf(obj, conditionTypes)
+	if len(f.statuses) > 0 && !slices.ContainsFunc(f.statuses, func(s string) bool { return strings.EqualFold(s, string(kstatus)) }) {
+		return false
+	}
+	if f.notReady && ready != "False" && ready != "Unknown" {
+		return false
+	}
+	return true
+}
+
+// apply returns a copy of objs that only has the matching objects under the roots, and the objects on the paths from
+// the roots to them. The roots are always kept.
+func (f treeFilter) apply(objs objectDirectory, roots []unstructured.Unstructured, conditionTypes []string) objectDirectory {
+	if !f.enabled() {
+		return objs
+	}
+	keep := make(map[types.UID]bool)
+	var visit func(obj unstructured.Unstructured) bool
+	visit = func(obj unstructured.Unstructured) bool {
+		if v, ok := keep[obj.GetUID()]; ok {
+			return v
+		}
+		keep[obj.GetUID()] = false // in case of cycles
+		match := f.matches(objs, obj, conditionTypes)
+		for _, child := range objs.children(obj.GetUID()) {
+			if visit(child.Unstructured) {
+				match = true
+			}
+		}
+		keep[obj.GetUID()] = match
+		return match
+	}
+	for _, root := range roots {
+		visit(root)
+	}
+
+	out := objectDirectory{
+		items:      objs.items,
+		ownership:  make(map[types.UID]map[types.UID]bool),
+		relations:  make(map[types.UID]map[types.UID]string),
+		unresolved: objs.unresolved,
+	}
+	for owner, owned := range objs.ownership {
+		for id := range owned {
+			if keep[id] {
+				if out.ownership[owner] == nil {
+					out.ownership[owner] = make(map[types.UID]bool)
+				}
+				out.ownership[owner][id] = true
+			}
+		}
+	}
+	for from, related := range objs.relations {
+		for id, typ := range related {
+			if keep[id] {
+				if out.relations[from] == nil {
+					out.relations[from] = make(map[types.UID]string)
+				}
+				out.relations[from][id] = typ
+			}
+		}
+	}
+	return out
+}
@@ -0,0 +1,118 @@
+package main
+
+import (
+	"slices"
+	"testing"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+func TestTreeFilter(t *testing.T) {
+	deploy := newTestObject("apps/v1", "Deployment", "app", "d1")
+	rs := newTestObject("apps/v1", "ReplicaSet", "app-1", "rs1", "d1")
+	ready := newTestObject("v1", "Pod", "app-1-a", "p1", "rs1")
+	ready.Object["status"] = map[string]interface{}{
+		"phase":      "Running",
+		"conditions": []interface{}{map[string]interface{}{"type": "Ready", "status": "True"}},
+	}
+	notReady := newTestObject("v1", "Pod", "app-1-b", "p2", "rs1")
+	notReady.Object["status"] = map[string]interface{}{
+		"phase":      "Running",
+		"conditions": []interface{}{map[string]interface{}{"type": "Ready", "status": "False", "reason": "ContainersNotReady"}},
+	}
+	secret := newTestObject("v1", "Secret", "token", "s1", "d1")
+	objs := newObjectDirectory([]unstructured.Unstructured{deploy, rs, ready, notReady, secret})
+	roots := []unstructured.Unstructured{deploy}
+
+	tests := []struct {
+		name   string
+		filter treeFilter
+		want   []string
+	}{
+		{
+			name: "no filter",
+			want: []string{"app", "app-1", "app-1-a", "app-1-b", "token"},
+		},
+		{
+			name:   "kinds",
+			filter: treeFilter{kinds: newKindSelector([]string{"secrets"})},
+			want:   []string{"app", "token"},
+		},
+		{
+			name:   "kind of an intermediate object",
+			filter: treeFilter{kinds: newKindSelector([]string{"rs"})},
+			want:   []string{"app", "app-1"},
+		},
+		{
+			name:   "status",
+			filter: treeFilter{statuses: []string{"inprogress"}},
+			want:   []string{"app", "app-1", "app-1-b"},
+		},
+		{
+			name:   "not ready pods",
+			filter: treeFilter{kinds: newKindSelector([]string{"pods"}), notReady: true},
+			want:   []string{"app", "app-1", "app-1-b"},
+		},
+		{
+			name:   "no matches",
+			filter: treeFilter{statuses: []string{"failed"}},
+			want:   []string{"app"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []string
+			for _, obj := range treeObjects(tt.filter.apply(objs, roots, []string{"Ready"}), roots) {
+				got = append(got, obj.GetName())
+			}
+			slices.Sort(got)
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTreeFilterStatusWithoutReadyCondition(t *testing.T) {
+	cron := newTestObject("batch/v1", "CronJob", "backup", "c1")
+	failed := newFailedJob("backup-1", "j1", "c1")
+	complete := newTestObject("batch/v1", "Job", "backup-2", "j2", "c1")
+	complete.Object["status"] = map[string]interface{}{
+		"conditions": []interface{}{map[string]interface{}{"type": "Complete", "status": "True"}},
+	}
+	deploy := newFailedDeployment("app", "d1")
+	rs := newTestObject("apps/v1", "ReplicaSet", "app-1", "rs1", "d1")
+	objs := newObjectDirectory([]unstructured.Unstructured{cron, failed, complete, deploy, rs})
+
+	filter := treeFilter{statuses: []string{"failed"}}
+	for _, tt := range []struct {
+		root, failed unstructured.Unstructured
+		want         []string
+	}{
+		{root: cron, failed: failed, want: []string{"backup", "backup-1"}},
+		{root: deploy, failed: deploy, want: []string{"app"}},
+	} {
+		roots := []unstructured.Unstructured{tt.root}
+		filtered := filter.apply(objs, roots, []string{"Ready"})
+		var got []string
+		for _, obj := range treeObjects(filtered, roots) {
+			got = append(got, obj.GetName())
+		}
+		slices.Sort(got)
+		if !slices.Equal(got, tt.want) {
+			t.Errorf("%s: got %v, want %v", tt.root.GetName(), got, tt.want)
+		}
+		if !filter.matches(objs, tt.failed, []string{"Ready"}) {
+			t.Errorf("failed %s doesn't match --only-status=failed", tt.failed.GetKind())
+		}
+	}
+}
+
+func TestValidateStatusFilters(t *testing.T) {
+	if err := validateStatusFilters([]string{"failed", "InProgress"}); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if err := validateStatusFilters([]string{"ready"}); err == nil {
+		t.Error("expected an error for an unknown status")
+	}
+}
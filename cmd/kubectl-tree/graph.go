@@ -0,0 +1,198 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/utils/ptr"
+	"sigs.k8s.io/cli-utils/pkg/kstatus/status"
+)
+
+const (
+	outputDot     = "dot"
+	outputMermaid = "mermaid"
+)
+
+// graphEdge is an edge from an object to an object under it in the graph.
+type graphEdge struct {
+	from, to types.UID
+
+	// controller is set for ownerReferences with controller: true.
+	controller bool
+
+	// relation is the type of the logical relationship, or empty for ownerReferences.
+	relation string
+}
+
+// ownershipGraph is the subgraph of the objects reachable from the roots, down to the maximum depth. Unlike the tree
+// view, objects with multiple owners appear only once.
+type ownershipGraph struct {
+	nodes []unstructured.Unstructured
+	edges []graphEdge
+}
+
+func newOwnershipGraph(objs objectDirectory, roots []unstructured.Unstructured, maxDepth int) ownershipGraph {
+	var g ownershipGraph
+	visited := make(map[types.UID]bool)
+	var visit func(obj unstructured.Unstructured, depth int)
+	visit = func(obj unstructured.Unstructured, depth int) {
+		if visited[obj.GetUID()] {
+			return
+		}
+		visited[obj.GetUID()] = true
+		g.nodes = append(g.nodes, obj)
+		if maxDepth > 0 && depth >= maxDepth {
+			return
+		}
+		for _, child := range objs.children(obj.GetUID()) {
+			g.edges = append(g.edges, graphEdge{
+				from:       obj.GetUID(),
+				to:         child.GetUID(),
+				controller: child.ownerRef != nil && ptr.Deref(child.ownerRef.Controller, false),
+				relation:   child.relation,
+			})
+			visit(child.Unstructured, depth+1)
+		}
+	}
+	for _, root := range roots {
+		visit(root, 0)
+	}
+	return g
+}
+
+// statusColors are the fill colors of the nodes by their kstatus.
+var statusColors = map[status.Status]string{
+	status.CurrentStatus:     "#c8e6c9",
+	status.InProgressStatus:  "#fff9c4",
+	status.FailedStatus:      "#ffcdd2",
+	status.TerminatingStatus: "#ffcdd2",
+	status.NotFoundStatus:    "#ffcdd2",
+}
+
+const defaultNodeColor = "#eeeeee"
+
+func nodeColor(s status.Status) string {
+	if c, ok := statusColors[s]; ok {
+		return c
+	}
+	return defaultNodeColor
+}
+
+// nodeIDs assigns short identifiers to the nodes, since UIDs are not valid identifiers in all formats.
+func (g ownershipGraph) nodeIDs() map[types.UID]string {
+	ids := make(map[types.UID]string, len(g.nodes))
+	for i, n := range g.nodes {
+		ids[n.GetUID()] = fmt.Sprintf("n%d", i)
+	}
+	return ids
+}
+
+// nodeLabel returns the lines describing the object in the graph.
+func nodeLabel(obj unstructured.Unstructured, ready ReadyStatus, kstatus status.Status) []string {
+	lines := []string{obj.GetKind() + "/" + obj.GetName()}
+	if obj.GetNamespace() != "" {
+		lines = append(lines, "ns: "+obj.GetNamespace())
+	}
+	var st []string
+	if ready != "" {
+		st = append(st, "ready: "+string(ready))
+	}
+	if kstatus != "" {
+		st = append(st, "status: "+string(kstatus))
+	}
+	if len(st) > 0 {
+		lines = append(lines, strings.Join(st, ", "))
+	}
+	return lines
+}
+
+// dotView prints the graph of the objects under the roots in Graphviz DOT format. Nodes are colored by their
+// kstatus, and edges of controller ownerReferences are drawn bold, while logical relationships are dashed.
+func dotView(out io.Writer, objs objectDirectory, roots []unstructured.Unstructured, opts treeOptions) error {
+	g := newOwnershipGraph(objs, roots, opts.depth)
+	ids := g.nodeIDs()
+
+	var b strings.Builder
+	b.WriteString("digraph tree {\n")
+	b.WriteString("  rankdir=LR;\n")
+	b.WriteString("  node [shape=box, style=\"rounded,filled\", fontname=\"Helvetica\"];\n")
+	for _, n := range g.nodes {
+		ready, _, kstatus := objs.statusOf(n, opts.conditionTypes)
+		fmt.Fprintf(&b, "  %s [label=%s, fillcolor=%q];\n", ids[n.GetUID()],
+			dotQuote(strings.Join(nodeLabel(n, ready, kstatus), "\n")), nodeColor(kstatus))
+	}
+	for _, e := range g.edges {
+		var attrs []string
+		switch {
+		case e.relation != "":
+			attrs = append(attrs, "style=dashed", "label="+dotQuote(e.relation))
+		case e.controller:
+			attrs = append(attrs, "style=bold", `label="controller"`)
+		}
+		fmt.Fprintf(&b, "  %s -> %s", ids[e.from], ids[e.to])
+		if len(attrs) > 0 {
+			fmt.Fprintf(&b, " [%s]", strings.Join(attrs, ", "))
+		}
+		b.WriteString(";\n")
+	}
+	b.WriteString("}\n")
+	_, err := io.WriteString(out, b.String())
+	return err
+}
+
+func dotQuote(s string) string {
+	s = strings.ReplaceAll(s, `\`, `\\`)
+	s = strings.ReplaceAll(s, `"`, `\"`)
+	return `"` + strings.ReplaceAll(s, "\n", `\n`) + `"`
+}
+
+// mermaidView prints the graph of the objects under the roots as a Mermaid flowchart. Nodes are colored by their
+// kstatus, and edges of controller ownerReferences are drawn thick, while logical relationships are dotted.
+func mermaidView(out io.Writer, objs objectDirectory, roots []unstructured.Unstructured, opts treeOptions) error {
+	g := newOwnershipGraph(objs, roots, opts.depth)
+	ids := g.nodeIDs()
+
+	var b strings.Builder
+	b.WriteString("flowchart LR\n")
+	classes := make(map[string][]string)
+	for _, n := range g.nodes {
+		ready, _, kstatus := objs.statusOf(n, opts.conditionTypes)
+		fmt.Fprintf(&b, "  %s[\"%s\"]\n", ids[n.GetUID()], mermaidEscape(strings.Join(nodeLabel(n, ready, kstatus), "<br/>")))
+		class := "status" + strings.ToLower(string(kstatus))
+		if kstatus == "" {
+			class = "statusnone"
+		}
+		classes[class] = append(classes[class], ids[n.GetUID()])
+	}
+	for _, e := range g.edges {
+		switch {
+		case e.relation != "":
+			fmt.Fprintf(&b, "  %s -. %s .-> %s\n", ids[e.from], mermaidEscape(e.relation), ids[e.to])
+		case e.controller:
+			fmt.Fprintf(&b, "  %s == controller ==> %s\n", ids[e.from], ids[e.to])
+		default:
+			fmt.Fprintf(&b, "  %s --> %s\n", ids[e.from], ids[e.to])
+		}
+	}
+	for _, s := range slices.Concat(status.Statuses, []status.Status{status.NotFoundStatus, ""}) {
+		class := "status" + strings.ToLower(string(s))
+		if s == "" {
+			class = "statusnone"
+		}
+		if len(classes[class]) == 0 {
+			continue
+		}
+		fmt.Fprintf(&b, "  classDef %s fill:%s\n", class, nodeColor(s))
+		fmt.Fprintf(&b, "  class %s %s\n", strings.Join(classes[class], ","), class)
+	}
+	_, err := io.WriteString(out, b.String())
+	return err
+}
+
+func mermaidEscape(s string) string {
+	return strings.ReplaceAll(s, `"`, "#quot;")
+}
@@ -0,0 +1,83 @@
+package main
+
+import (
+	"bytes"
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/utils/ptr"
+)
+
+// newTestGraph returns a failed Deployment with a controller-owned ReplicaSet and Pod, a ConfigMap owned by the
+// Deployment without being its controller (with a name that needs escaping), and the Pod using the ConfigMap.
+func newTestGraph(t *testing.T) (objectDirectory, []unstructured.Unstructured) {
+	t.Helper()
+	deploy := newFailedDeployment("app", "d1")
+	rs := newTestObject("apps/v1", "ReplicaSet", "app-1", "rs1")
+	rs.SetOwnerReferences([]metav1.OwnerReference{{Kind: "Deployment", Name: "app", UID: "d1", Controller: ptr.To(true)}})
+	pod := newTestObject("v1", "Pod", "app-1-a", "p1")
+	pod.SetOwnerReferences([]metav1.OwnerReference{{Kind: "ReplicaSet", Name: "app-1", UID: "rs1", Controller: ptr.To(true)}})
+	pod.Object["spec"] = map[string]interface{}{
+		"volumes": []interface{}{map[string]interface{}{"name": "config", "configMap": map[string]interface{}{"name": `say-"hi"`}}},
+	}
+	pod.Object["status"] = map[string]interface{}{
+		"phase":      "Running",
+		"conditions": []interface{}{map[string]interface{}{"type": "Ready", "status": "True"}},
+	}
+	cm := newTestObject("v1", "ConfigMap", `say-"hi"`, "cm1", "d1")
+	objs := newObjectDirectory([]unstructured.Unstructured{deploy, rs, pod, cm})
+	objs.resolveRelations([]string{"config"})
+	return objs, []unstructured.Unstructured{deploy}
+}
+
+func TestDotView(t *testing.T) {
+	objs, roots := newTestGraph(t)
+	var buf bytes.Buffer
+	if err := dotView(&buf, objs, roots, treeOptions{conditionTypes: []string{"Ready"}}); err != nil {
+		t.Fatal(err)
+	}
+	want := `digraph tree {
+  rankdir=LR;
+  node [shape=box, style="rounded,filled", fontname="Helvetica"];
+  n0 [label="Deployment/app\nns: default\nstatus: Failed", fillcolor="#ffcdd2"];
+  n1 [label="ConfigMap/say-\"hi\"\nns: default\nstatus: Current", fillcolor="#c8e6c9"];
+  n2 [label="ReplicaSet/app-1\nns: default\nstatus: InProgress", fillcolor="#fff9c4"];
+  n3 [label="Pod/app-1-a\nns: default\nready: True, status: Current", fillcolor="#c8e6c9"];
+  n0 -> n1;
+  n0 -> n2 [style=bold, label="controller"];
+  n2 -> n3 [style=bold, label="controller"];
+  n3 -> n1 [style=dashed, label="uses"];
+}
+`
+	if got := buf.String(); got != want {
+		t.Errorf("got:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestMermaidView(t *testing.T) {
+	objs, roots := newTestGraph(t)
+	var buf bytes.Buffer
+	if err := mermaidView(&buf, objs, roots, treeOptions{conditionTypes: []string{"Ready"}}); err != nil {
+		t.Fatal(err)
+	}
+	want := `flowchart LR
+  n0["Deployment/app<br/>ns: default<br/>status: Failed"]
+  n1["ConfigMap/say-#quot;hi#quot;<br/>ns: default<br/>status: Current"]
+  n2["ReplicaSet/app-1<br/>ns: default<br/>status: InProgress"]
+  n3["Pod/app-1-a<br/>ns: default<br/>ready: True, status: Current"]
+  n0 --> n1
+  n0 == controller ==> n2
+  n2 == controller ==> n3
+  n3 -. uses .-> n1
+  classDef statusinprogress fill:#fff9c4
+  class n2 statusinprogress
+  classDef statusfailed fill:#ffcdd2
+  class n0 statusfailed
+  classDef statuscurrent fill:#c8e6c9
+  class n1,n3 statuscurrent
+`
+	if got := buf.String(); got != want {
+		t.Errorf("got:\n%s\nwant:\n%s", got, want)
+	}
+}
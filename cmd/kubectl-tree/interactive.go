@@ -0,0 +1,538 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/fatih/color"
+	"golang.org/x/term"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/duration"
+	"k8s.io/utils/ptr"
+	"sigs.k8s.io/yaml"
+)
+
+const (
+	interactiveRefreshInterval = 5 * time.Second
+	interactiveResizeInterval  = 250 * time.Millisecond
+
+	enterAltScreen = "\x1b[?1049h\x1b[?25l"
+	exitAltScreen  = "\x1b[?25h\x1b[?1049l"
+	cursorHome     = "\x1b[H"
+	clearLine      = "\x1b[K"
+
+	interactiveHelp = "↑/↓ move  ←/→ collapse/expand  enter toggle  J/K scroll details  r refresh  q quit"
+)
+
+var (
+	bold      = color.New(color.Bold)
+	highlight = color.New(color.ReverseVideo)
+)
+
+// treeLoader queries the objects, roots and events of the trees shown in the interactive mode.
+type treeLoader func() (objectDirectory, []unstructured.Unstructured, eventIndex, error)
+
+// tuiRow is an object shown in the tree of the interactive mode.
+type tuiRow struct {
+	obj    treeChild
+	prefix string
+	depth  int
+
+	// parent is the index of the row of the parent, or -1 for the roots.
+	parent int
+
+	// children is the number of children of the object, or 0 if it's a reference to an object shown elsewhere.
+	children int
+
+	// ref describes the reference if the object is already shown elsewhere, e.g. a cycle.
+	ref string
+}
+
+// tuiCommand is what the interactive mode does after a key is pressed, other than updating the screen.
+type tuiCommand int
+
+const (
+	tuiNone tuiCommand = iota
+	tuiRefresh
+	tuiQuit
+)
+
+// segment is a part of a line printed in a color, or without a color if c is nil.
+type segment struct {
+	text string
+	c    *color.Color
+}
+
+// tui is the state of the interactive mode.
+type tui struct {
+	opts   treeOptions
+	objs   objectDirectory
+	roots  []unstructured.Unstructured
+	events eventIndex
+
+	// collapsed are the objects collapsed or expanded by the user. Other objects are collapsed if they are deeper
+	// than --depth or their kind is in --collapse.
+	collapsed map[types.UID]bool
+
+	rows      []tuiRow
+	selected  int
+	top       int // the first row of the tree on the screen
+	detailTop int // the first line of the details on the screen
+	updated   time.Time
+	err       error
+}
+
+func newTUI(opts treeOptions) *tui {
+	return &tui{opts: opts, collapsed: make(map[types.UID]bool)}
+}
+
+// runInteractive shows the trees in a full-screen terminal UI until the user quits, reloading them periodically.
+func runInteractive(load treeLoader, opts treeOptions) error {
+	in, out := int(os.Stdin.Fd()), int(os.Stdout.Fd())
+	if !term.IsTerminal(in) || !term.IsTerminal(out) {
+		return errors.New("interactive mode requires a terminal")
+	}
+	t := newTUI(opts)
+	objs, roots, events, err := load()
+	if err != nil {
+		return err
+	}
+	t.update(objs, roots, events, nil)
+
+	state, err := term.MakeRaw(in)
+	if err != nil {
+		return fmt.Errorf("failed to set up the terminal: %w", err)
+	}
+	defer term.Restore(in, state)
+	fmt.Fprint(os.Stdout, enterAltScreen)
+	defer fmt.Fprint(os.Stdout, exitAltScreen)
+
+	keys := make(chan string)
+	go readKeys(os.Stdin, keys)
+
+	type loadResult struct {
+		objs   objectDirectory
+		roots  []unstructured.Unstructured
+		events eventIndex
+		err    error
+	}
+	results := make(chan loadResult, 1)
+	var loading bool
+	reload := func() {
+		if loading {
+			return
+		}
+		loading = true
+		go func() {
+			var r loadResult
+			r.objs, r.roots, r.events, r.err = load()
+			results <- r
+		}()
+	}
+	refresh := time.NewTicker(interactiveRefreshInterval)
+	defer refresh.Stop()
+	resize := time.NewTicker(interactiveResizeInterval)
+	defer resize.Stop()
+
+	var width, height int
+	dirty := true
+	for {
+		w, h, err := term.GetSize(out)
+		if err != nil || w == 0 || h == 0 {
+			w, h = 80, 24
+		}
+		if dirty || w != width || h != height {
+			fmt.Fprint(color.Output, t.render(w, h))
+			width, height, dirty = w, h, false
+		}
+		select {
+		case key, ok := <-keys:
+			if !ok {
+				return nil
+			}
+			switch t.handleKey(key, height-2) {
+			case tuiQuit:
+				return nil
+			case tuiRefresh:
+				reload()
+			}
+			dirty = true
+		case <-refresh.C:
+			reload()
+		case r := <-results:
+			loading = false
+			t.update(r.objs, r.roots, r.events, r.err)
+			dirty = true
+		case <-resize.C:
+		}
+	}
+}
+
+// readKeys sends the keys read from r to keys until reading fails.
+func readKeys(r io.Reader, keys chan<- string) {
+	defer close(keys)
+	buf := make([]byte, 64)
+	for {
+		n, err := r.Read(buf)
+		if err != nil {
+			return
+		}
+		for _, key := range parseKeys(buf[:n]) {
+			keys <- key
+		}
+	}
+}
+
+// escapeKeys are the names of the keys sent as escape sequences, without the leading ESC.
+var escapeKeys = map[string]string{
+	"[A": "up", "[B": "down", "[C": "right", "[D": "left",
+	"OA": "up", "OB": "down", "OC": "right", "OD": "left",
+	"[5~": "pgup", "[6~": "pgdown",
+	"[H": "home", "[1~": "home", "OH": "home",
+	"[F": "end", "[4~": "end", "OF": "end",
+}
+
+// parseKeys returns the names of the keys in the input of a terminal in raw mode, e.g. "up", "enter" or "q". Unknown
+// escape sequences are skipped.
+func parseKeys(b []byte) []string {
+	var keys []string
+	for len(b) > 0 {
+		switch {
+		case b[0] == 0x1b && len(b) > 2 && (b[1] == '[' || b[1] == 'O'):
+			end := 2
+			for end < len(b)-1 && (b[end] < 0x40 || b[end] > 0x7e) {
+				end++
+			}
+			if key, ok := escapeKeys[string(b[1:end+1])]; ok {
+				keys = append(keys, key)
+			}
+			b = b[end+1:]
+			continue
+		case b[0] == 0x1b:
+			keys = append(keys, "esc")
+		case b[0] == '\r' || b[0] == '\n':
+			keys = append(keys, "enter")
+		case b[0] == ' ':
+			keys = append(keys, "space")
+		case b[0] == 0x03:
+			keys = append(keys, "ctrl-c")
+		default:
+			keys = append(keys, string(b[0]))
+		}
+		b = b[1:]
+	}
+	return keys
+}
+
+// update replaces the trees with newly loaded ones, keeping the selected object if it's still there. If loading
+// failed, the previous trees are kept and the error is shown.
+func (t *tui) update(objs objectDirectory, roots []unstructured.Unstructured, events eventIndex, err error) {
+	t.err = err
+	if err != nil {
+		return
+	}
+	var prev types.UID
+	if t.selected < len(t.rows) {
+		prev = t.rows[t.selected].obj.GetUID()
+	}
+	objs = t.opts.filter.apply(objs, roots, t.opts.conditionTypes)
+	t.objs, t.roots, t.events, t.updated = objs, roots, events, time.Now()
+	t.flatten()
+	if i := slices.IndexFunc(t.rows, func(r tuiRow) bool { return r.obj.GetUID() == prev && r.ref == "" }); i >= 0 {
+		t.selected = i
+	}
+	t.selected = max(0, min(t.selected, len(t.rows)-1))
+}
+
+func (t *tui) isCollapsed(obj unstructured.Unstructured, depth int) bool {
+	if v, ok := t.collapsed[obj.GetUID()]; ok {
+		return v
+	}
+	return (t.opts.depth > 0 && depth >= t.opts.depth) ||
+		t.opts.collapse.matches(obj)
+}
+
+// flatten builds the rows of the trees, skipping the descendants of collapsed objects.
+func (t *tui) flatten() {
+	t.rows = nil
+	visits := make(map[types.UID]visitState)
+	var add func(prefix string, obj treeChild, depth, parent int)
+	add = func(prefix string, obj treeChild, depth, parent int) {
+		row := tuiRow{obj: obj, prefix: printPrefix(prefix), depth: depth, parent: parent}
+		switch visits[obj.GetUID()] {
+		case visiting:
+			row.ref = fmt.Sprintf("↻ cycle back to %s/%s", obj.GetKind(), obj.GetName())
+		case visited:
+			row.ref = seeAbove(obj)
+		}
+		if row.ref != "" {
+			t.rows = append(t.rows, row)
+			return
+		}
+		chs := t.objs.children(obj.GetUID())
+		row.children = len(chs)
+		t.rows = append(t.rows, row)
+		if t.isCollapsed(obj.Unstructured, depth) {
+			return
+		}
+		visits[obj.GetUID()] = visiting
+		defer func() { visits[obj.GetUID()] = visited }()
+		idx := len(t.rows) - 1
+		for i, child := range chs {
+			p := prefix + firstElemPrefix
+			if i == len(chs)-1 {
+				p = prefix + lastElemPrefix
+			}
+			add(p, child, depth+1, idx)
+		}
+	}
+	for _, root := range t.roots {
+		add("", treeChild{Unstructured: root}, 0, -1)
+	}
+}
+
+// handleKey updates the state for a key pressed by the user. pageSize is the number of rows of the tree on the screen.
+func (t *tui) handleKey(key string, pageSize int) tuiCommand {
+	if len(t.rows) == 0 {
+		switch key {
+		case "q", "esc", "ctrl-c":
+			return tuiQuit
+		case "r":
+			return tuiRefresh
+		}
+		return tuiNone
+	}
+	prev := t.selected
+	row := t.rows[t.selected]
+	expanded := row.children > 0 && !t.isCollapsed(row.obj.Unstructured, row.depth)
+	switch key {
+	case "q", "esc", "ctrl-c":
+		return tuiQuit
+	case "r":
+		return tuiRefresh
+	case "up", "k":
+		t.selected--
+	case "down", "j":
+		t.selected++
+	case "pgup":
+		t.selected -= max(1, pageSize)
+	case "pgdown":
+		t.selected += max(1, pageSize)
+	case "home", "g":
+		t.selected = 0
+	case "end", "G":
+		t.selected = len(t.rows) - 1
+	case "right", "l":
+		if expanded {
+			t.selected++
+		} else if row.children > 0 {
+			t.toggle(row)
+		}
+	case "left", "h":
+		if expanded {
+			t.toggle(row)
+		} else if row.parent >= 0 {
+			t.selected = row.parent
+		}
+	case "enter", "space":
+		if row.children > 0 {
+			t.toggle(row)
+		}
+	case "J":
+		t.detailTop++
+	case "K":
+		t.detailTop = max(0, t.detailTop-1)
+	}
+	t.selected = max(0, min(t.selected, len(t.rows)-1))
+	if t.selected != prev {
+		t.detailTop = 0
+	}
+	return tuiNone
+}
+
+// toggle collapses or expands the object of the row.
+func (t *tui) toggle(row tuiRow) {
+	t.collapsed[row.obj.GetUID()] = !t.isCollapsed(row.obj.Unstructured, row.depth)
+	t.flatten()
+}
+
+// render returns the escape sequences and the text to draw the screen of the specified size.
+func (t *tui) render(width, height int) string {
+	var b strings.Builder
+	b.WriteString(cursorHome)
+
+	header := []segment{{text: fmt.Sprintf("kubectl tree: %d object(s) in %d tree(s), updated %s",
+		len(treeObjects(t.objs, t.roots)), len(t.roots), t.updated.Format(time.TimeOnly)), c: bold}}
+	if t.err != nil {
+		header = append(header, segment{text: "  refresh failed: " + t.err.Error(), c: red})
+	}
+	b.WriteString(fit(width, header...) + clearLine + "\r\n")
+
+	body := max(0, height-2)
+	if t.selected < t.top {
+		t.top = t.selected
+	}
+	if t.selected >= t.top+body {
+		t.top = t.selected - body + 1
+	}
+	left := width / 2
+	right := max(0, width-left-1)
+	var details [][]segment
+	if t.selected < len(t.rows) {
+		details = t.details(t.rows[t.selected].obj.Unstructured)
+	}
+	t.detailTop = max(0, min(t.detailTop, len(details)-body))
+	for i := 0; i < body; i++ {
+		l, r := fit(left), fit(right)
+		if row := t.top + i; row < len(t.rows) {
+			l = t.treeLine(row, left)
+		}
+		if d := t.detailTop + i; d < len(details) {
+			r = fit(right, details[d]...)
+		}
+		b.WriteString(l + gray.Sprint("│") + r + clearLine + "\r\n")
+	}
+	b.WriteString(fit(width, segment{text: interactiveHelp, c: gray}) + clearLine)
+	return b.String()
+}
+
+// treeLine returns the line of a row in the tree, highlighted if it's selected.
+func (t *tui) treeLine(i, width int) string {
+	row := t.rows[i]
+	marker := "  "
+	switch {
+	case row.children > 0 && t.isCollapsed(row.obj.Unstructured, row.depth):
+		marker = "▸ "
+	case row.children > 0:
+		marker = "▾ "
+	}
+	segs := []segment{{text: row.prefix, c: gray}, {text: marker}}
+	if row.ref != "" {
+		segs = append(segs, segment{text: row.ref, c: gray})
+	} else {
+		if row.obj.relation != "" {
+			segs = append(segs, segment{text: fmt.Sprintf("[%s] ", row.obj.relation), c: cyan})
+		}
+		segs = append(segs, segment{text: row.obj.GetKind() + "/"}, segment{text: row.obj.GetName(), c: bold})
+		ready, _, kstatus := t.objs.statusOf(row.obj.Unstructured, t.opts.conditionTypes)
+		if ready != "" {
+			segs = append(segs, segment{text: "  Ready=" + string(ready), c: readyColor(ready)})
+		}
+		if kstatus != "" {
+			segs = append(segs, segment{text: "  " + string(kstatus), c: statusColor(kstatus)})
+		}
+		if n := len(t.events[row.obj.GetUID()]); n > 0 {
+			segs = append(segs, segment{text: fmt.Sprintf("  ⚠ %d", n), c: yellow})
+		}
+	}
+	if i != t.selected {
+		return fit(width, append([]segment{{text: " "}}, segs...)...)
+	}
+	// the colors of the segments would end the highlight
+	for j := range segs {
+		segs[j].c = highlight
+	}
+	return fit(width, append([]segment{{text: "›", c: highlight}}, segs...)...)
+}
+
+// details returns the lines describing an object: its status, owners, conditions, events and YAML.
+func (t *tui) details(obj unstructured.Unstructured) [][]segment {
+	var lines [][]segment
+	add := func(segs ...segment) { lines = append(lines, segs) }
+	field := func(name, value string) {
+		if value != "" {
+			add(segment{text: name + ": ", c: gray}, segment{text: value})
+		}
+	}
+
+	add(segment{text: fmt.Sprintf(" %s/%s", obj.GetKind(), obj.GetName()), c: bold})
+	field(" namespace", obj.GetNamespace())
+	ready, reason, kstatus := t.objs.statusOf(obj, t.opts.conditionTypes)
+	if ready != "" {
+		add(segment{text: " ready: ", c: gray}, segment{text: strings.TrimSpace(string(ready) + " " + string(reason)), c: readyColor(ready)})
+	}
+	if kstatus != "" {
+		add(segment{text: " status: ", c: gray}, segment{text: string(kstatus), c: statusColor(kstatus)})
+	}
+	if c := obj.GetCreationTimestamp(); !c.IsZero() {
+		field(" age", duration.HumanDuration(time.Since(c.Time)))
+	}
+	for _, ref := range obj.GetOwnerReferences() {
+		owner := ref.Kind + "/" + ref.Name
+		if ptr.Deref(ref.Controller, false) {
+			owner += " (controller)"
+		}
+		field(" owner", owner)
+	}
+
+	add()
+	add(segment{text: " CONDITIONS", c: bold})
+	conditions, _, _ := unstructured.NestedSlice(obj.Object, "status", "conditions")
+	if len(conditions) == 0 {
+		add(segment{text: "  <none>", c: gray})
+	}
+	for _, c := range conditions {
+		s := ReadyStatus(nestedString(c, "status"))
+		text := fmt.Sprintf("  %s=%s", nestedString(c, "type"), s)
+		if r := nestedString(c, "reason"); r != "" {
+			text += " " + r
+		}
+		if m := nestedString(c, "message"); m != "" {
+			text += ": " + m
+		}
+		add(segment{text: text, c: readyColor(s)})
+	}
+
+	add()
+	add(segment{text: " EVENTS", c: bold})
+	if evs := t.events[obj.GetUID()]; len(evs) == 0 {
+		add(segment{text: "  <none>", c: gray})
+	} else {
+		for _, ev := range evs {
+			add(segment{text: "  ⚠ " + ev.String(), c: yellow})
+		}
+	}
+
+	add()
+	add(segment{text: " YAML", c: bold})
+	obj = *obj.DeepCopy()
+	unstructured.RemoveNestedField(obj.Object, "metadata", "managedFields")
+	b, err := yaml.Marshal(obj.Object)
+	if err != nil {
+		add(segment{text: "  " + err.Error(), c: red})
+		return lines
+	}
+	for _, l := range strings.Split(strings.TrimSuffix(string(b), "\n"), "\n") {
+		add(segment{text: "  " + l})
+	}
+	return lines
+}
+
+// fit joins the segments, truncated or padded with spaces to width columns.
+func fit(width int, segs ...segment) string {
+	var b strings.Builder
+	n := 0
+	for _, s := range segs {
+		if n >= width {
+			break
+		}
+		r := []rune(s.text)
+		if n+len(r) > width {
+			r = r[:width-n]
+		}
+		n += len(r)
+		if s.c != nil {
+			b.WriteString(s.c.Sprint(string(r)))
+		} else {
+			b.WriteString(string(r))
+		}
+	}
+	b.WriteString(strings.Repeat(" ", max(0, width-n)))
+	return b.String()
+}
@@ -0,0 +1,79 @@
+package main
+
+import (
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/fatih/color"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+func TestParseKeys(t *testing.T) {
+	tests := []struct {
+		in   string
+		want []string
+	}{
+		{in: "j", want: []string{"j"}},
+		{in: "\x1b[A\x1b[B", want: []string{"up", "down"}},
+		{in: "\x1b[6~q", want: []string{"pgdown", "q"}},
+		{in: "\x1b[2~\r", want: []string{"enter"}},
+		{in: "\x1b", want: []string{"esc"}},
+		{in: " \x03", want: []string{"space", "ctrl-c"}},
+	}
+	for _, tt := range tests {
+		if got := parseKeys([]byte(tt.in)); !slices.Equal(got, tt.want) {
+			t.Errorf("parseKeys(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestTUI(t *testing.T) {
+	defer func(v bool) { color.NoColor = v }(color.NoColor)
+	color.NoColor = true
+	deploy := newTestObject("apps/v1", "Deployment", "app", "d1")
+	rs := newTestObject("apps/v1", "ReplicaSet", "app-1", "rs1", "d1")
+	pod := newTestObject("v1", "Pod", "app-1-a", "p1", "rs1")
+	pod.Object["status"] = map[string]interface{}{
+		"phase":      "Running",
+		"conditions": []interface{}{map[string]interface{}{"type": "Ready", "status": "False", "reason": "ContainersNotReady"}},
+	}
+	objs := newObjectDirectory([]unstructured.Unstructured{deploy, rs, pod})
+
+	names := func(tu *tui) []string {
+		var out []string
+		for _, r := range tu.rows {
+			out = append(out, r.obj.GetName())
+		}
+		return out
+	}
+
+	tu := newTUI(treeOptions{conditionTypes: []string{"Ready"}, collapse: newKindSelector([]string{"rs"})})
+	tu.update(objs, []unstructured.Unstructured{deploy}, nil, nil)
+	if got := names(tu); !slices.Equal(got, []string{"app", "app-1"}) {
+		t.Fatalf("rows with --collapse=rs = %v", got)
+	}
+
+	for _, key := range []string{"down", "right", "right"} {
+		tu.handleKey(key, 10)
+	}
+	if got := names(tu); !slices.Equal(got, []string{"app", "app-1", "app-1-a"}) || tu.selected != 2 {
+		t.Fatalf("rows after expanding = %v, selected %d", got, tu.selected)
+	}
+
+	screen := tu.render(100, 40)
+	for _, want := range []string{"3 object(s) in 1 tree(s)", "›  └─  Pod/app-1-a  Ready=False  InProgress", "ready: False ContainersNotReady", "kind: Pod"} {
+		if !strings.Contains(screen, want) {
+			t.Errorf("screen does not contain %q:\n%s", want, screen)
+		}
+	}
+
+	tu.handleKey("left", 10)
+	tu.handleKey("left", 10)
+	if got := names(tu); !slices.Equal(got, []string{"app", "app-1"}) || tu.selected != 1 {
+		t.Fatalf("rows after collapsing = %v, selected %d", got, tu.selected)
+	}
+	if tu.handleKey("q", 10) != tuiQuit {
+		t.Fatal("q did not quit")
+	}
+}
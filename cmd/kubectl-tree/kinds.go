@@ -0,0 +1,68 @@
+package main
+
+import (
+	"fmt"
+	"slices"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+// kindSelector matches objects by the resource type arguments of flags like --collapse (e.g. "rs", "deployments.apps"
+// or a CRD short name). When querying a cluster, the arguments are resolved to kinds through API discovery, so
+// unknown resource types are rejected. Otherwise (e.g. with --from-file), they are matched with matchesKind.
+type kindSelector struct {
+	args []string
+
+	// kinds are the resolved kinds of args, or nil if they are not resolved.
+	kinds map[schema.GroupKind]bool
+}
+
+func newKindSelector(args []string) kindSelector { return kindSelector{args: args} }
+
+func (s kindSelector) empty() bool { return len(s.args) == 0 }
+
+// matches reports whether the kind of obj is one of the resource types.
+func (s kindSelector) matches(obj unstructured.Unstructured) bool {
+	if s.kinds != nil {
+		return s.kinds[obj.GroupVersionKind().GroupKind()]
+	}
+	return slices.ContainsFunc(s.args, func(arg string) bool { return matchesKind(arg, obj) })
+}
+
+// resolve returns the selector with the resource types resolved to kinds through the discovered API resources, or an
+// error for the resource types that are not found.
+func (s kindSelector) resolve(rm *resourceMap) (kindSelector, error) {
+	if s.empty() {
+		return s, nil
+	}
+	kinds := make(map[schema.GroupKind]bool)
+	for _, arg := range s.args {
+		apis := rm.lookup(arg)
+		if len(apis) == 0 {
+			return s, fmt.Errorf("unknown resource type %q", arg)
+		}
+		for _, a := range apis {
+			kinds[schema.GroupKind{Group: a.gv.Group, Kind: a.r.Kind}] = true
+		}
+	}
+	return kindSelector{args: s.args, kinds: kinds}, nil
+}
+
+// resolveKinds returns the options with the resource types of the flags that select objects by kind resolved through
+// the discovered API resources.
+func (opts treeOptions) resolveKinds(rm *resourceMap) (treeOptions, error) {
+	var err error
+	if opts.collapse, err = opts.collapse.resolve(rm); err != nil {
+		return opts, fmt.Errorf("invalid value for --%s: %w", collapseFlag, err)
+	}
+	if opts.filter.kinds, err = opts.filter.kinds.resolve(rm); err != nil {
+		return opts, fmt.Errorf("invalid value for --%s: %w", showKindsFlag, err)
+	}
+	return opts, nil
+}
+
+// selectsKinds reports whether any of the flags that select objects by kind is set.
+func (opts treeOptions) selectsKinds() bool {
+	return !opts.collapse.empty() || !opts.filter.kinds.empty()
+}
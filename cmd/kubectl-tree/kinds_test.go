@@ -0,0 +1,65 @@
+package main
+
+import (
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+func TestKindSelector(t *testing.T) {
+	rm := &resourceMap{m: make(resourceNameLookup)}
+	for _, a := range []apiResource{
+		{gv: schema.GroupVersion{Group: "apps", Version: "v1"}, r: metav1.APIResource{Name: "replicasets", SingularName: "replicaset", Kind: "ReplicaSet", ShortNames: []string{"rs"}}},
+		{gv: schema.GroupVersion{Group: "kustomize.toolkit.fluxcd.io", Version: "v1"}, r: metav1.APIResource{Name: "kustomizations", SingularName: "kustomization", Kind: "Kustomization", ShortNames: []string{"ks"}}},
+		{gv: schema.GroupVersion{Version: "v1"}, r: metav1.APIResource{Name: "endpoints", SingularName: "endpoints", Kind: "Endpoints"}},
+	} {
+		for _, name := range apiNames(a.r, a.gv) {
+			rm.m[name] = append(rm.m[name], a)
+		}
+		rm.list = append(rm.list, a)
+	}
+	ks := newTestObject("kustomize.toolkit.fluxcd.io/v1", "Kustomization", "app", "k1")
+	rs := newTestObject("apps/v1", "ReplicaSet", "app-1", "rs1")
+	ep := newTestObject("v1", "Endpoints", "app", "e1")
+
+	tests := []struct {
+		args    []string
+		want    []bool // matches ks, rs, ep
+		wantErr bool
+	}{
+		{args: []string{"ks"}, want: []bool{true, false, false}},
+		{args: []string{"rs", "endpoints"}, want: []bool{false, true, true}},
+		{args: []string{"kustomizations.kustomize.toolkit.fluxcd.io"}, want: []bool{true, false, false}},
+		{args: []string{"Kustomization"}, want: []bool{true, false, false}},
+		{args: []string{"replicasetz"}, wantErr: true},
+	}
+	for _, tt := range tests {
+		s, err := newKindSelector(tt.args).resolve(rm)
+		if (err != nil) != tt.wantErr {
+			t.Fatalf("%v: unexpected error: %v", tt.args, err)
+		}
+		if tt.wantErr {
+			continue
+		}
+		for i, obj := range []unstructured.Unstructured{ks, rs, ep} {
+			if got := s.matches(obj); got != tt.want[i] {
+				t.Errorf("%v: matches %s = %v, want %v", tt.args, obj.GetKind(), got, tt.want[i])
+			}
+		}
+	}
+
+	opts := treeOptions{collapse: newKindSelector([]string{"rs"}), filter: treeFilter{kinds: newKindSelector([]string{"kss"})}}
+	if _, err := opts.resolveKinds(rm); err == nil || err.Error() != `invalid value for --show-kinds: unknown resource type "kss"` {
+		t.Errorf("unexpected error for an unknown kind in --show-kinds: %v", err)
+	}
+
+	// without discovery (e.g. with --from-file), the short names of CRDs are not known
+	if s := newKindSelector([]string{"ks"}); s.matches(ks) {
+		t.Error("unresolved short name of a CRD matches")
+	}
+	if s := newKindSelector([]string{"rs"}); !s.matches(rs) {
+		t.Error("unresolved short name of a built-in kind doesn't match")
+	}
+}
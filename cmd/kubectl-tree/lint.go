@@ -0,0 +1,118 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/utils/ptr"
+)
+
+// lintIssue is a problem with the ownerReferences of an object.
+type lintIssue struct {
+	obj     unstructured.Unstructured
+	message string
+}
+
+func (i lintIssue) String() string {
+	name := i.obj.GetKind() + "/" + i.obj.GetName()
+	if ns := i.obj.GetNamespace(); ns != "" {
+		name = ns + "/" + name
+	}
+	return name + ": " + i.message
+}
+
+// lintTrees checks the ownerReferences of the objects in the trees of the roots for cycles, owners that don't exist,
+// owners in a different namespace and multiple controller references.
+func lintTrees(objs objectDirectory, roots []unstructured.Unstructured) []lintIssue {
+	var issues []lintIssue
+	for _, obj := range treeObjects(objs, roots) {
+		if _, ok := objs.unresolved[obj.GetUID()]; ok {
+			continue // placeholder of a missing owner, reported on the objects referencing it
+		}
+		var controllers []string
+		for _, ref := range obj.GetOwnerReferences() {
+			refName := ref.Kind + "/" + ref.Name
+			if ptr.Deref(ref.Controller, false) {
+				controllers = append(controllers, refName)
+			}
+			owner, ok := objs.items[ref.UID]
+			reason, unresolved := objs.unresolved[ref.UID]
+			switch {
+			case !ok || reason == notFoundReason:
+				issues = append(issues, lintIssue{obj, fmt.Sprintf("owner %s (uid %s) does not exist, or its kind was not queried", refName, ref.UID)})
+			case unresolved:
+				// the owner exists but could not be retrieved (e.g. forbidden)
+			case owner.GetNamespace() != "" && owner.GetNamespace() != obj.GetNamespace():
+				issues = append(issues, lintIssue{obj, fmt.Sprintf("owner %s is in a different namespace (%s)", refName, owner.GetNamespace())})
+			}
+		}
+		if len(controllers) > 1 {
+			issues = append(issues, lintIssue{obj, fmt.Sprintf("multiple controller ownerReferences: %s", strings.Join(controllers, ", "))})
+		}
+	}
+	for _, cycle := range ownershipCycles(objs, roots) {
+		var names []string
+		for _, id := range cycle {
+			o := objs.items[id]
+			names = append(names, o.GetKind()+"/"+o.GetName())
+		}
+		names = append(names, names[0])
+		issues = append(issues, lintIssue{objs.items[cycle[0]], "ownerReference cycle: " + strings.Join(names, " → ")})
+	}
+	return issues
+}
+
+// ownershipCycles returns the ownership cycles reachable from the roots, each starting with the object with the
+// lowest UID in it, so that the same cycle is reported once.
+func ownershipCycles(objs objectDirectory, roots []unstructured.Unstructured) [][]types.UID {
+	var cycles [][]types.UID
+	seen := make(map[string]bool)
+	visits := make(map[types.UID]visitState)
+	var path []types.UID
+	var visit func(id types.UID)
+	visit = func(id types.UID) {
+		visits[id] = visiting
+		path = append(path, id)
+		for _, child := range objs.ownedBy(id) {
+			switch visits[child.GetUID()] {
+			case visiting:
+				cycle := slices.Clone(path[slices.Index(path, child.GetUID()):])
+				lowest := slices.Index(cycle, slices.Min(cycle))
+				cycle = append(cycle[lowest:], cycle[:lowest]...)
+				key := fmt.Sprint(cycle)
+				if !seen[key] {
+					seen[key] = true
+					cycles = append(cycles, cycle)
+				}
+			case notVisited:
+				visit(child.GetUID())
+			}
+		}
+		path = path[:len(path)-1]
+		visits[id] = visited
+	}
+	for _, root := range roots {
+		if visits[root.GetUID()] == notVisited {
+			visit(root.GetUID())
+		}
+	}
+	return cycles
+}
+
+// checkLint prints the problems found in the trees to out, and returns an error if there are any.
+func checkLint(out io.Writer, objs objectDirectory, roots []unstructured.Unstructured) error {
+	issues := lintTrees(objs, roots)
+	if len(issues) == 0 {
+		fmt.Fprintln(out, green.Sprint("No ownerReference problems found."))
+		return nil
+	}
+	fmt.Fprintf(out, "%s\n", red.Sprintf("%d ownerReference problem(s) found:", len(issues)))
+	for _, i := range issues {
+		fmt.Fprintf(out, "  %s\n", i)
+	}
+	return fmt.Errorf("found %d ownerReference problem(s)", len(issues))
+}
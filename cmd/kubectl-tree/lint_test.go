@@ -0,0 +1,75 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/utils/ptr"
+)
+
+func TestLintTrees(t *testing.T) {
+	deploy := newTestObject("apps/v1", "Deployment", "app", "d1")
+	rs := newTestObject("apps/v1", "ReplicaSet", "app-1", "rs1")
+	rs.SetOwnerReferences([]metav1.OwnerReference{{Kind: "Deployment", Name: "app", UID: "d1", Controller: ptr.To(true)}})
+	pod := newTestObject("v1", "Pod", "app-1-a", "p1")
+	pod.SetOwnerReferences([]metav1.OwnerReference{
+		{Kind: "ReplicaSet", Name: "app-1", UID: "rs1", Controller: ptr.To(true)},
+		{Kind: "ReplicaSet", Name: "gone", UID: "rs-gone", Controller: ptr.To(true)},
+	})
+	secret := newTestObject("v1", "Secret", "token", "s1")
+	secret.SetNamespace("other")
+	secret.SetOwnerReferences([]metav1.OwnerReference{{Kind: "ReplicaSet", Name: "app-1", UID: "rs1"}})
+	cyclic := newTestObject("apps/v1", "ReplicaSet", "app-1", "rs1")
+	cyclic.SetOwnerReferences([]metav1.OwnerReference{
+		{Kind: "Deployment", Name: "app", UID: "d1", Controller: ptr.To(true)},
+		{Kind: "Pod", Name: "app-1-a", UID: "p1"},
+	})
+
+	tests := []struct {
+		name string
+		objs []unstructured.Unstructured
+		want []string
+	}{
+		{
+			name: "no problems",
+			objs: []unstructured.Unstructured{deploy, rs},
+		},
+		{
+			name: "missing owner and multiple controllers",
+			objs: []unstructured.Unstructured{deploy, rs, pod},
+			want: []string{
+				"default/Pod/app-1-a: owner ReplicaSet/gone (uid rs-gone) does not exist",
+				"default/Pod/app-1-a: multiple controller ownerReferences: ReplicaSet/app-1, ReplicaSet/gone",
+			},
+		},
+		{
+			name: "owner in other namespace",
+			objs: []unstructured.Unstructured{deploy, rs, secret},
+			want: []string{"other/Secret/token: owner ReplicaSet/app-1 is in a different namespace (default)"},
+		},
+		{
+			name: "cycle",
+			objs: []unstructured.Unstructured{deploy, cyclic, pod},
+			want: []string{
+				"default/Pod/app-1-a: owner ReplicaSet/gone (uid rs-gone) does not exist",
+				"default/Pod/app-1-a: multiple controller ownerReferences: ReplicaSet/app-1, ReplicaSet/gone",
+				"default/Pod/app-1-a: ownerReference cycle: Pod/app-1-a → ReplicaSet/app-1 → Pod/app-1-a",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			issues := lintTrees(newObjectDirectory(tt.objs), []unstructured.Unstructured{deploy})
+			if len(issues) != len(tt.want) {
+				t.Fatalf("got %d issues (%v), want %d", len(issues), issues, len(tt.want))
+			}
+			for i, issue := range issues {
+				if !strings.HasPrefix(issue.String(), tt.want[i]) {
+					t.Errorf("issue %d = %q, want %q", i, issue, tt.want[i])
+				}
+			}
+		})
+	}
+}
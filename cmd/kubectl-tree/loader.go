@@ -0,0 +1,292 @@
+package main
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	utiljson "k8s.io/apimachinery/pkg/util/json"
+	"k8s.io/apimachinery/pkg/util/yaml"
+	"k8s.io/klog"
+)
+
+// loadObjects reads objects from the specified files, directories (recursively) and tar archives (optionally gzipped),
+// or from stdin if the path is "-". Files can contain multiple YAML documents or JSON objects, and List kinds are
+// flattened into their items.
+func loadObjects(paths []string) ([]unstructured.Unstructured, error) {
+	var out []unstructured.Unstructured
+	add := func(objs []unstructured.Unstructured) { out = append(out, objs...) }
+	for _, p := range paths {
+		if p == "-" {
+			objs, err := decodeObjects(os.Stdin)
+			if err != nil {
+				return nil, fmt.Errorf("failed to read objects from stdin: %w", err)
+			}
+			add(objs)
+			continue
+		}
+		err := filepath.WalkDir(p, func(path string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			if d.IsDir() {
+				return nil
+			}
+			// files in directories are only read if they look like manifests, but explicitly specified files always are
+			if path != p && !isManifestFile(path) && !isTarFile(path) {
+				klog.V(4).Infof("skipping file %s", path)
+				return nil
+			}
+			objs, err := loadFile(path)
+			if err != nil {
+				return fmt.Errorf("failed to read objects from %s: %w", path, err)
+			}
+			klog.V(3).Infof("loaded %d objects from %s", len(objs), path)
+			add(objs)
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	return out, nil
+}
+
+func isManifestFile(path string) bool {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml", ".json":
+		return true
+	}
+	return false
+}
+
+func isTarFile(path string) bool {
+	p := strings.ToLower(path)
+	return strings.HasSuffix(p, ".tar") || strings.HasSuffix(p, ".tar.gz") || strings.HasSuffix(p, ".tgz")
+}
+
+func loadFile(path string) ([]unstructured.Unstructured, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var r io.Reader = f
+	if strings.HasSuffix(strings.ToLower(path), "gz") {
+		gz, err := gzip.NewReader(f)
+		if err != nil {
+			return nil, err
+		}
+		defer gz.Close()
+		r = gz
+	}
+	if isTarFile(path) {
+		return decodeTar(r)
+	}
+	return decodeObjects(r)
+}
+
+// decodeTar reads objects from the manifest files in a tar archive.
+func decodeTar(r io.Reader) ([]unstructured.Unstructured, error) {
+	var out []unstructured.Unstructured
+	tr := tar.NewReader(r)
+	for {
+		hdr, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			return out, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		if hdr.Typeflag != tar.TypeReg || !isManifestFile(hdr.Name) {
+			continue
+		}
+		objs, err := decodeObjects(tr)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", hdr.Name, err)
+		}
+		out = append(out, objs...)
+	}
+}
+
+// decodeObjects reads a stream of YAML documents or JSON objects.
+func decodeObjects(r io.Reader) ([]unstructured.Unstructured, error) {
+	var out []unstructured.Unstructured
+	dec := yaml.NewYAMLOrJSONDecoder(r, 4096)
+	for {
+		var raw json.RawMessage
+		if err := dec.Decode(&raw); err != nil {
+			if errors.Is(err, io.EOF) {
+				return out, nil
+			}
+			return nil, err
+		}
+		if len(bytes.TrimSpace(raw)) == 0 {
+			continue // empty document
+		}
+		// decode numbers as int64 where possible like the API client does, instead of float64
+		var m map[string]interface{}
+		if err := utiljson.Unmarshal(raw, &m); err != nil {
+			return nil, err
+		}
+		if len(m) == 0 {
+			continue // empty document
+		}
+		obj := unstructured.Unstructured{Object: m}
+		if obj.GetKind() == "" {
+			klog.V(4).Infof("skipping document without kind")
+			continue
+		}
+		if !obj.IsList() {
+			out = append(out, obj)
+			continue
+		}
+		err := obj.EachListItem(func(o runtime.Object) error {
+			if item, ok := o.(*unstructured.Unstructured); ok {
+				out = append(out, *item)
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+}
+
+// offlineObjects prepares loaded objects for building a directory: objects without an UID (e.g. manifests that have
+// not been applied yet) get one derived from their identity, and duplicates are dropped keeping the last one.
+func offlineObjects(objs []unstructured.Unstructured) []unstructured.Unstructured {
+	seen := make(map[types.UID]int)
+	var out []unstructured.Unstructured
+	for _, obj := range objs {
+		if obj.GetUID() == "" {
+			obj.SetUID(types.UID(strings.Join([]string{obj.GetAPIVersion(), obj.GetKind(), obj.GetNamespace(), obj.GetName()}, "/")))
+		}
+		if i, ok := seen[obj.GetUID()]; ok {
+			out[i] = obj
+			continue
+		}
+		seen[obj.GetUID()] = len(out)
+		out = append(out, obj)
+	}
+	return out
+}
+
+// shortNames are the well-known short names of built-in kinds, since there's no API discovery when working offline.
+var shortNames = map[string]string{
+	"cm":     "configmap",
+	"cj":     "cronjob",
+	"crd":    "customresourcedefinition",
+	"ds":     "daemonset",
+	"deploy": "deployment",
+	"ep":     "endpoints",
+	"ev":     "event",
+	"hpa":    "horizontalpodautoscaler",
+	"ing":    "ingress",
+	"netpol": "networkpolicy",
+	"no":     "node",
+	"ns":     "namespace",
+	"pdb":    "poddisruptionbudget",
+	"po":     "pod",
+	"pv":     "persistentvolume",
+	"pvc":    "persistentvolumeclaim",
+	"rc":     "replicationcontroller",
+	"rs":     "replicaset",
+	"sa":     "serviceaccount",
+	"sts":    "statefulset",
+	"svc":    "service",
+}
+
+// matchesKind reports whether the resource type argument (e.g. "deploy", "deployments.apps" or
+// "kservice.v1.serving.knative.dev") refers to the kind of obj.
+func matchesKind(arg string, obj unstructured.Unstructured) bool {
+	arg = strings.ToLower(arg)
+	name, group, _ := strings.Cut(arg, ".")
+	gvk := obj.GroupVersionKind()
+	if group != "" && group != strings.ToLower(gvk.Group) && group != strings.ToLower(gvk.Version+"."+gvk.Group) {
+		return false
+	}
+	if v, ok := shortNames[name]; ok {
+		name = v
+	}
+	kind := strings.ToLower(gvk.Kind)
+	return name == kind || name == pluralize(kind)
+}
+
+// pluralize guesses the plural resource name of a lowercase kind.
+func pluralize(kind string) string {
+	switch {
+	case strings.HasSuffix(kind, "s"), strings.HasSuffix(kind, "x"), strings.HasSuffix(kind, "ch"), strings.HasSuffix(kind, "sh"):
+		return kind + "es"
+	case strings.HasSuffix(kind, "y") && !strings.HasSuffix(kind, "ay") && !strings.HasSuffix(kind, "ey"):
+		return strings.TrimSuffix(kind, "y") + "ies"
+	}
+	return kind + "s"
+}
+
+// findRoots resolves the KIND [NAME...] or KIND/NAME... arguments against the loaded objects, in the specified
+// namespace (or all namespaces if empty). It returns whether a single object was specified by name.
+func findRoots(objs []unstructured.Unstructured, args []string, namespace string) ([]unstructured.Unstructured, bool, error) {
+	type target struct{ kind, name string }
+	var targets []target
+	switch {
+	case strings.Contains(args[0], "/"):
+		for _, a := range args {
+			kind, name, ok := strings.Cut(a, "/")
+			if !ok || kind == "" || name == "" {
+				return nil, false, fmt.Errorf("arguments in resource/name form must have a single resource and name, got %q", a)
+			}
+			targets = append(targets, target{kind, name})
+		}
+	case len(args) == 1:
+		targets = append(targets, target{kind: args[0]})
+	default:
+		for _, a := range args[1:] {
+			targets = append(targets, target{args[0], a})
+		}
+	}
+
+	var out []unstructured.Unstructured
+	for _, t := range targets {
+		var found bool
+		for _, obj := range objs {
+			if !matchesKind(t.kind, obj) || (t.name != "" && obj.GetName() != t.name) {
+				continue
+			}
+			if namespace != "" && obj.GetNamespace() != "" && obj.GetNamespace() != namespace {
+				continue
+			}
+			found = true
+			out = append(out, obj)
+		}
+		if !found && t.name != "" {
+			return nil, false, fmt.Errorf("%s %q not found in the loaded objects", t.kind, t.name)
+		}
+	}
+	if len(out) == 0 {
+		return nil, false, fmt.Errorf("no resources found")
+	}
+	sortObjects(out)
+	single := len(targets) == 1 && targets[0].name != "" && len(out) == 1
+	return out, single, nil
+}
+
+// sortObjects sorts objects by namespace, then by kind and name.
+func sortObjects(objs []unstructured.Unstructured) {
+	slices.SortStableFunc(objs, func(a, b unstructured.Unstructured) int {
+		return strings.Compare(a.GetNamespace()+"/"+a.GetKind()+"/"+a.GetName(), b.GetNamespace()+"/"+b.GetKind()+"/"+b.GetName())
+	})
+}
@@ -0,0 +1,130 @@
+package main
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+const testManifests = `apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: app
+  namespace: default
+  uid: d1
+---
+apiVersion: apps/v1
+kind: ReplicaSet
+metadata:
+  name: app-1
+  namespace: default
+  uid: rs1
+  ownerReferences:
+  - apiVersion: apps/v1
+    kind: Deployment
+    name: app
+    uid: d1
+---
+`
+
+const testList = `{
+  "apiVersion": "v1",
+  "kind": "List",
+  "items": [
+    {"apiVersion": "v1", "kind": "Pod", "metadata": {"name": "app-1-a", "namespace": "default", "uid": "p1",
+      "ownerReferences": [{"apiVersion": "apps/v1", "kind": "ReplicaSet", "name": "app-1", "uid": "rs1"}]}},
+    {"apiVersion": "v1", "kind": "Pod", "metadata": {"name": "other", "namespace": "other"}}
+  ]
+}`
+
+func TestLoadObjects(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "app.yaml"), []byte(testManifests), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "README.md"), []byte("not a manifest"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	writeTestTarball(t, filepath.Join(dir, "dump.tar.gz"), map[string]string{"pods.json": testList})
+
+	loaded, err := loadObjects([]string{dir})
+	if err != nil {
+		t.Fatal(err)
+	}
+	objs := offlineObjects(loaded)
+	if len(objs) != 4 {
+		t.Fatalf("expected 4 objects, got %d", len(objs))
+	}
+
+	roots, single, err := findRoots(objs, []string{"deploy", "app"}, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !single || len(roots) != 1 || roots[0].GetUID() != "d1" {
+		t.Fatalf("expected deployment app as the single root, got %d roots (single=%v)", len(roots), single)
+	}
+	od := newObjectDirectory(objs)
+	if rs := od.ownedBy("d1"); len(rs) != 1 || len(od.ownedBy(rs[0].GetUID())) != 1 {
+		t.Fatalf("expected deployment -> replicaset -> pod hierarchy")
+	}
+
+	roots, single, err = findRoots(objs, []string{"pods"}, "other")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if single || len(roots) != 1 || roots[0].GetUID() != "v1/Pod/other/other" {
+		t.Fatalf("expected pod without uid in namespace other, got %v", roots)
+	}
+
+	if _, _, err := findRoots(objs, []string{"rs/missing"}, ""); err == nil {
+		t.Fatalf("expected error for missing object")
+	}
+}
+
+func TestMatchesKind(t *testing.T) {
+	obj := newTestObject("apps/v1", "Deployment", "app", "d1")
+	tests := []struct {
+		arg  string
+		want bool
+	}{
+		{"deployment", true},
+		{"Deployments", true},
+		{"deploy", true},
+		{"deployment.apps", true},
+		{"deployments.v1.apps", true},
+		{"deployment.batch", false},
+		{"rs", false},
+	}
+	for _, tt := range tests {
+		if got := matchesKind(tt.arg, obj); got != tt.want {
+			t.Errorf("matchesKind(%q) = %v, want %v", tt.arg, got, tt.want)
+		}
+	}
+}
+
+func writeTestTarball(t *testing.T, path string, files map[string]string) {
+	t.Helper()
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	gz := gzip.NewWriter(f)
+	tw := tar.NewWriter(gz)
+	for name, content := range files {
+		if err := tw.WriteHeader(&tar.Header{Name: name, Mode: 0o644, Size: int64(len(content)), Typeflag: tar.TypeReg}); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := tw.Write([]byte(content)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := gz.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
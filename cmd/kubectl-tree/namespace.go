@@ -1,13 +1,25 @@
 package main
 
-func getNamespace() string {
-	if v := *cf.Namespace; v != "" {
+import "k8s.io/cli-runtime/pkg/genericclioptions"
+
+// getNamespace returns the namespace specified with --namespace, or the namespace of the kubeconfig context.
+func getNamespace(f *genericclioptions.ConfigFlags) string {
+	if v := *f.Namespace; v != "" {
 		return v
 	}
-	clientConfig := cf.ToRawKubeConfigLoader()
+	clientConfig := f.ToRawKubeConfigLoader()
 	defaultNamespace, _, err := clientConfig.Namespace()
 	if err != nil {
 		defaultNamespace = "default"
 	}
 	return defaultNamespace
 }
+
+// queryNamespace returns the namespace to query the objects in, or "" to query all namespaces (and the
+// non-namespaced objects).
+func queryNamespace(f *genericclioptions.ConfigFlags, allNs bool) string {
+	if allNs {
+		return ""
+	}
+	return getNamespace(f)
+}
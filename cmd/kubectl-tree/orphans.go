@@ -0,0 +1,239 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/fatih/color"
+	"github.com/gosuri/uitable"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/duration"
+	"k8s.io/klog"
+	"k8s.io/utils/ptr"
+	"sigs.k8s.io/yaml"
+)
+
+const outputName = "name"
+
+var orphansCmd = &cobra.Command{
+	Use:          "orphans",
+	SilenceUsage: true,
+	Short:        "List objects whose owners no longer exist",
+	Long: "List objects with ownerReferences to objects that no longer exist, grouped by namespace and owner kind.\n\n" +
+		"Owners of kinds that are not queried (e.g. excluded with --resources, or cluster-scoped kinds without -A) are\n" +
+		"not reported as missing.",
+	Example: "  kubectl tree orphans\n" +
+		"  kubectl tree orphans -A --resources=replicasets,pods\n" +
+		"  kubectl tree orphans -n my-app -o name | xargs kubectl delete -n my-app\n" +
+		"  kubectl tree orphans -A -o json | kubectl delete -f -",
+	Args: cobra.NoArgs,
+	RunE: runOrphans,
+}
+
+// orphan is an object with ownerReferences to objects that don't exist.
+type orphan struct {
+	unstructured.Unstructured
+	missing []metav1.OwnerReference
+}
+
+func runOrphans(command *cobra.Command, _ []string) error {
+	if err := setColor(command); err != nil {
+		return err
+	}
+	allNs, err := command.Flags().GetBool(allNamespacesFlag)
+	if err != nil {
+		allNs = false
+	}
+	labelSelector, err := command.Flags().GetString(selectorFlag)
+	if err != nil {
+		return err
+	}
+	apiGroups, err := command.Flags().GetStringSlice(apiGroupsFlag)
+	if err != nil {
+		return err
+	}
+	resources, err := command.Flags().GetStringSlice(resourcesFlag)
+	if err != nil {
+		return err
+	}
+	outputFormat, err := command.Flags().GetString(outputFlag)
+	if err != nil {
+		return err
+	}
+	switch outputFormat {
+	case "", outputName, outputJSON, outputYAML:
+	default:
+		return errors.Errorf("invalid value for --%s: %q (supported: %s, %s, %s)", outputFlag, outputFormat, outputName, outputJSON, outputYAML)
+	}
+	refresh, err := command.Flags().GetBool(refreshDiscoveryFlag)
+	if err != nil {
+		return err
+	}
+	cacheTTL, err := command.Flags().GetDuration(discoveryCacheTTLFlag)
+	if err != nil {
+		return err
+	}
+	files, err := command.Flags().GetStringSlice(fromFileFlag)
+	if err != nil {
+		return err
+	}
+
+	var apiObjects []unstructured.Unstructured
+	queried := make(map[schema.GroupKind]bool)
+	if len(files) > 0 {
+		loaded, err := loadObjects(files)
+		if err != nil {
+			return err
+		}
+		namespace := ptr.Deref(cf.Namespace, "")
+		for _, obj := range offlineObjects(loaded) {
+			// all kinds in the files are considered to be complete
+			queried[obj.GroupVersionKind().GroupKind()] = true
+			if allNs || namespace == "" || obj.GetNamespace() == namespace {
+				apiObjects = append(apiObjects, obj)
+			}
+		}
+	} else {
+		dyn, dc, err := newClients(cf, refresh, cacheTTL)
+		if err != nil {
+			return err
+		}
+		apis, err := findAPIs(dc, apiGroups, resources)
+		if err != nil {
+			return err
+		}
+		var versions map[schema.GroupVersionResource]string
+		apiObjects, versions, err = listAllResources(dyn, apis.resources(), queryNamespace(cf, allNs), "")
+		if err != nil {
+			return fmt.Errorf("error while querying api objects: %w", err)
+		}
+		for _, api := range apis.resources() {
+			if _, ok := versions[api.GroupVersionResource()]; ok {
+				queried[schema.GroupKind{Group: api.gv.Group, Kind: api.r.Kind}] = true
+			}
+		}
+	}
+	klog.V(2).Infof("found total %d api objects, %d kinds", len(apiObjects), len(queried))
+
+	orphans := findOrphans(newObjectDirectory(apiObjects), queried)
+	if labelSelector != "" {
+		// the owners are not filtered by the selector, as they would be reported missing otherwise
+		orphans, err = filterOrphans(orphans, labelSelector)
+		if err != nil {
+			return err
+		}
+	}
+	return printOrphans(color.Output, outputFormat, orphans)
+}
+
+// findOrphans returns the objects that have ownerReferences to objects that are not in objs, sorted by namespace,
+// the kind of the first missing owner, and then by kind and name. Owners of kinds that are not queried are assumed
+// to exist.
+func findOrphans(objs objectDirectory, queried map[schema.GroupKind]bool) []orphan {
+	var out []orphan
+	for _, obj := range objs.items {
+		var missing []metav1.OwnerReference
+		for _, ref := range obj.GetOwnerReferences() {
+			if _, ok := objs.items[ref.UID]; ok {
+				continue
+			}
+			gv, err := schema.ParseGroupVersion(ref.APIVersion)
+			if err != nil || !queried[schema.GroupKind{Group: gv.Group, Kind: ref.Kind}] {
+				klog.V(4).Infof("owner %s/%s of %s/%s is of a kind that is not queried", ref.Kind, ref.Name, obj.GetKind(), obj.GetName())
+				continue
+			}
+			missing = append(missing, ref)
+		}
+		if len(missing) > 0 {
+			out = append(out, orphan{Unstructured: obj, missing: missing})
+		}
+	}
+	sortOrphans(out)
+	return out
+}
+
+func sortOrphans(orphans []orphan) {
+	key := func(o orphan) string {
+		return strings.Join([]string{o.GetNamespace(), o.missing[0].Kind, o.GetKind(), o.GetName()}, "/")
+	}
+	slices.SortFunc(orphans, func(a, b orphan) int { return strings.Compare(key(a), key(b)) })
+}
+
+// filterOrphans returns the orphans that match the label selector.
+func filterOrphans(orphans []orphan, labelSelector string) ([]orphan, error) {
+	sel, err := labels.Parse(labelSelector)
+	if err != nil {
+		return nil, fmt.Errorf("invalid label selector: %w", err)
+	}
+	return slices.DeleteFunc(orphans, func(o orphan) bool { return !sel.Matches(labels.Set(o.GetLabels())) }), nil
+}
+
+// printOrphans prints the orphans in the specified format: a table grouped by namespace and owner kind by default,
+// resource/name lines with "name", or a List with json and yaml, which can be passed to kubectl delete -f.
+func printOrphans(out io.Writer, format string, orphans []orphan) error {
+	switch format {
+	case outputName:
+		for _, o := range orphans {
+			gk := o.GroupVersionKind().GroupKind()
+			fmt.Fprintf(out, "%s/%s\n", strings.ToLower(gk.String()), o.GetName())
+		}
+		return nil
+	case outputJSON, outputYAML:
+		list := unstructured.UnstructuredList{Object: map[string]interface{}{"apiVersion": "v1", "kind": "List"}}
+		for _, o := range orphans {
+			list.Items = append(list.Items, o.Unstructured)
+		}
+		var b []byte
+		var err error
+		if format == outputYAML {
+			b, err = yaml.Marshal(list.UnstructuredContent())
+		} else {
+			b, err = json.MarshalIndent(list.UnstructuredContent(), "", "  ")
+			b = append(b, '\n')
+		}
+		if err != nil {
+			return fmt.Errorf("failed to encode objects as %s: %w", format, err)
+		}
+		_, err = out.Write(b)
+		return err
+	}
+
+	if len(orphans) == 0 {
+		fmt.Fprintln(out, "No objects with missing owners found.")
+		return nil
+	}
+	tbl := uitable.New()
+	tbl.Separator = "  "
+	tbl.AddRow("NAMESPACE", "OWNER KIND", "NAME", "MISSING OWNERS", "AGE")
+	for i, o := range orphans {
+		if i > 0 && (o.GetNamespace() != orphans[i-1].GetNamespace() || o.missing[0].Kind != orphans[i-1].missing[0].Kind) {
+			tbl.AddRow()
+		}
+		var missing []string
+		for _, ref := range o.missing {
+			missing = append(missing, fmt.Sprintf("%s/%s", ref.Kind, ref.Name))
+		}
+		age := "<unknown>"
+		if c := o.GetCreationTimestamp(); !c.IsZero() {
+			age = duration.HumanDuration(time.Since(c.Time))
+		}
+		tbl.AddRow(o.GetNamespace(), o.missing[0].Kind, fmt.Sprintf("%s/%s", o.GetKind(), color.New(color.Bold).Sprint(o.GetName())),
+			red.Sprint(strings.Join(missing, ", ")), age)
+	}
+	fmt.Fprintln(out, tbl)
+	return nil
+}
+
+func init() {
+	orphansCmd.Flags().StringP(outputFlag, "o", "", "Output format. One of: name (resource/name lines for kubectl delete), json, yaml (a List of the objects). When not set, the objects are printed as a table.")
+	rootCmd.AddCommand(orphansCmd)
+}
@@ -0,0 +1,37 @@
+package main
+
+import (
+	"bytes"
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+func TestFindOrphans(t *testing.T) {
+	rs := newTestObject("apps/v1", "ReplicaSet", "app-1", "rs1")
+	rs.SetOwnerReferences([]metav1.OwnerReference{{APIVersion: "apps/v1", Kind: "Deployment", Name: "app", UID: "d1"}})
+	pod := newTestObject("v1", "Pod", "app-1-a", "p1")
+	pod.SetOwnerReferences([]metav1.OwnerReference{{APIVersion: "apps/v1", Kind: "ReplicaSet", Name: "app-1", UID: "rs1"}})
+	orphanedPod := newTestObject("v1", "Pod", "old-a", "p2")
+	orphanedPod.SetOwnerReferences([]metav1.OwnerReference{{APIVersion: "apps/v1", Kind: "ReplicaSet", Name: "old", UID: "rs-gone"}})
+	crdPod := newTestObject("v1", "Pod", "crd-a", "p3")
+	crdPod.SetOwnerReferences([]metav1.OwnerReference{{APIVersion: "example.com/v1", Kind: "Thing", Name: "thing", UID: "t1"}})
+
+	objs := newObjectDirectory([]unstructured.Unstructured{rs, pod, orphanedPod, crdPod})
+	queried := map[schema.GroupKind]bool{
+		{Group: "apps", Kind: "Deployment"}: true,
+		{Group: "apps", Kind: "ReplicaSet"}: true,
+		{Kind: "Pod"}:                       true,
+	}
+	orphans := findOrphans(objs, queried)
+
+	var buf bytes.Buffer
+	if err := printOrphans(&buf, outputName, orphans); err != nil {
+		t.Fatal(err)
+	}
+	if want := "replicaset.apps/app-1\npod/old-a\n"; buf.String() != want {
+		t.Fatalf("got orphans:\n%s\nwant:\n%s", buf.String(), want)
+	}
+}
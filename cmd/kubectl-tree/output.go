@@ -0,0 +1,128 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"slices"
+	"time"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/duration"
+	"sigs.k8s.io/cli-utils/pkg/kstatus/status"
+	"sigs.k8s.io/yaml"
+)
+
+const (
+	outputJSON = "json"
+	outputYAML = "yaml"
+)
+
+// treeNode is the serializable form of an object and its descendants, used for structured output.
+type treeNode struct {
+	APIVersion        string        `json:"apiVersion"`
+	Kind              string        `json:"kind"`
+	Namespace         string        `json:"namespace,omitempty"`
+	Name              string        `json:"name"`
+	UID               types.UID     `json:"uid"`
+	Ready             ReadyStatus   `json:"ready,omitempty"`
+	Reason            Reason        `json:"reason,omitempty"`
+	Status            status.Status `json:"status,omitempty"`
+	CreationTimestamp *time.Time    `json:"creationTimestamp,omitempty"`
+	Age               string        `json:"age,omitempty"`
+	Relation          string        `json:"relation,omitempty"`
+	Cycle             bool          `json:"cycle,omitempty"`
+	Ref               string        `json:"ref,omitempty"`
+	Owners            []string      `json:"owners,omitempty"`
+	Events            []objectEvent `json:"events,omitempty"`
+	Children          []treeNode    `json:"children,omitempty"`
+}
+
+// outputFormats are the supported values of --output, besides the default table view and custom columns.
+var outputFormats = []string{outputJSON, outputYAML, outputDot, outputMermaid, outputWide}
+
+// validOutputFormat reports whether the value of --output is supported.
+func validOutputFormat(f string) bool {
+	return f == "" || slices.Contains(outputFormats, f)
+}
+
+// buildTreeNode converts the hierarchy under obj into a treeNode, down to the maximum depth in opts. Like in the table
+// view, children that are already converted (e.g. objects with multiple owners) are added as a reference to the first
+// occurrence with their owners, and children that are ancestors of obj (i.e. there's an ownerReference cycle) are
+// marked as such, both without their children.
+func buildTreeNode(objs objectDirectory, obj unstructured.Unstructured, depth int, opts treeOptions, visits map[types.UID]visitState) treeNode {
+	visits[obj.GetUID()] = visiting
+	defer func() { visits[obj.GetUID()] = visited }()
+	ready, reason, kstatus := objs.statusOf(obj, opts.conditionTypes)
+	n := treeNode{
+		APIVersion: obj.GetAPIVersion(),
+		Kind:       obj.GetKind(),
+		Namespace:  obj.GetNamespace(),
+		Name:       obj.GetName(),
+		UID:        obj.GetUID(),
+		Ready:      ready,
+		Reason:     reason,
+		Status:     kstatus,
+		Events:     opts.events[obj.GetUID()],
+	}
+	if c := obj.GetCreationTimestamp(); !c.IsZero() {
+		t := c.UTC()
+		n.CreationTimestamp = &t
+		n.Age = duration.HumanDuration(time.Since(c.Time))
+	}
+	if opts.depth > 0 && depth >= opts.depth {
+		return n
+	}
+	for _, child := range objs.children(obj.GetUID()) {
+		var c treeNode
+		switch visits[child.GetUID()] {
+		case visiting:
+			c = treeNode{APIVersion: child.GetAPIVersion(), Kind: child.GetKind(), Namespace: child.GetNamespace(),
+				Name: child.GetName(), UID: child.GetUID(), Cycle: true}
+		case visited:
+			c = treeNode{APIVersion: child.GetAPIVersion(), Kind: child.GetKind(), Namespace: child.GetNamespace(),
+				Name: child.GetName(), UID: child.GetUID(), Ref: child.GetKind() + "/" + child.GetName()}
+			if owners := ownerNames(child.Unstructured); len(owners) > 1 {
+				c.Owners = owners
+			}
+		default:
+			c = buildTreeNode(objs, child.Unstructured, depth+1, opts, visits)
+		}
+		c.Relation = child.relation
+		n.Children = append(n.Children, c)
+	}
+	return n
+}
+
+// structuredView prints object hierarchy of each root to out stream in the specified format (json or yaml). If single
+// is true, the only root is printed as an object, otherwise the trees are printed as a list.
+func structuredView(out io.Writer, format string, objs objectDirectory, roots []unstructured.Unstructured, single bool, opts treeOptions) error {
+	var v any
+	visits := make(map[types.UID]visitState)
+	if single && len(roots) == 1 {
+		v = buildTreeNode(objs, roots[0], 0, opts, visits)
+	} else {
+		nodes := make([]treeNode, 0, len(roots))
+		for _, obj := range roots {
+			nodes = append(nodes, buildTreeNode(objs, obj, 0, opts, visits))
+		}
+		v = nodes
+	}
+	var b []byte
+	var err error
+	switch format {
+	case outputJSON:
+		b, err = json.MarshalIndent(v, "", "  ")
+		b = append(b, '\n')
+	case outputYAML:
+		b, err = yaml.Marshal(v)
+	default:
+		return fmt.Errorf("unsupported output format %q", format)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to encode tree as %s: %w", format, err)
+	}
+	_, err = out.Write(b)
+	return err
+}
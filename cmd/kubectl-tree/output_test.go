@@ -0,0 +1,101 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+func newTestObject(apiVersion, kind, name string, uid types.UID, owners ...types.UID) unstructured.Unstructured {
+	var obj unstructured.Unstructured
+	obj.SetAPIVersion(apiVersion)
+	obj.SetKind(kind)
+	obj.SetNamespace("default")
+	obj.SetName(name)
+	obj.SetUID(uid)
+	var refs []metav1.OwnerReference
+	for _, o := range owners {
+		refs = append(refs, metav1.OwnerReference{UID: o})
+	}
+	obj.SetOwnerReferences(refs)
+	return obj
+}
+
+func TestStructuredView(t *testing.T) {
+	deploy := newTestObject("apps/v1", "Deployment", "app", "d1")
+	objs := newObjectDirectory([]unstructured.Unstructured{
+		deploy,
+		newTestObject("apps/v1", "ReplicaSet", "app-1", "rs1", "d1"),
+		newTestObject("v1", "Pod", "app-1-b", "p2", "rs1"),
+		newTestObject("v1", "Pod", "app-1-a", "p1", "rs1"),
+	})
+
+	var buf bytes.Buffer
+	if err := structuredView(&buf, outputJSON, objs, []unstructured.Unstructured{deploy}, true, treeOptions{conditionTypes: []string{"Ready"}}); err != nil {
+		t.Fatal(err)
+	}
+	var got treeNode
+	if err := json.Unmarshal(buf.Bytes(), &got); err != nil {
+		t.Fatalf("output is not valid json: %v\n%s", err, buf.String())
+	}
+	if got.Kind != "Deployment" || got.UID != "d1" {
+		t.Fatalf("unexpected root node: %+v", got)
+	}
+	if len(got.Children) != 1 || got.Children[0].Kind != "ReplicaSet" {
+		t.Fatalf("expected a single ReplicaSet child, got: %+v", got.Children)
+	}
+	pods := got.Children[0].Children
+	if len(pods) != 2 || pods[0].Name != "app-1-a" || pods[1].Name != "app-1-b" {
+		t.Fatalf("expected sorted pods under the ReplicaSet, got: %+v", pods)
+	}
+
+	buf.Reset()
+	if err := structuredView(&buf, outputYAML, objs, []unstructured.Unstructured{deploy}, false, treeOptions{conditionTypes: []string{"Ready"}, depth: 1}); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.HasPrefix(buf.Bytes(), []byte("- apiVersion: apps/v1")) || !bytes.Contains(buf.Bytes(), []byte("kind: ReplicaSet")) {
+		t.Fatalf("unexpected yaml output:\n%s", buf.String())
+	}
+}
+
+func TestStructuredViewSharedChild(t *testing.T) {
+	deploy := newTestObject("apps/v1", "Deployment", "app", "d1")
+	shared := newTestObject("v1", "Pod", "shared", "p1")
+	shared.SetOwnerReferences([]metav1.OwnerReference{
+		{APIVersion: "apps/v1", Kind: "ReplicaSet", Name: "app-1", UID: "rs1"},
+		{APIVersion: "apps/v1", Kind: "ReplicaSet", Name: "app-2", UID: "rs2"},
+	})
+	objs := newObjectDirectory([]unstructured.Unstructured{
+		deploy,
+		newTestObject("apps/v1", "ReplicaSet", "app-1", "rs1", "d1"),
+		newTestObject("apps/v1", "ReplicaSet", "app-2", "rs2", "d1"),
+		shared,
+		newTestObject("v1", "Secret", "shared-child", "s1", "p1"),
+	})
+
+	var buf bytes.Buffer
+	if err := structuredView(&buf, outputJSON, objs, []unstructured.Unstructured{deploy}, true, treeOptions{conditionTypes: []string{"Ready"}}); err != nil {
+		t.Fatal(err)
+	}
+	var got treeNode
+	if err := json.Unmarshal(buf.Bytes(), &got); err != nil {
+		t.Fatalf("output is not valid json: %v\n%s", err, buf.String())
+	}
+	if len(got.Children) != 2 {
+		t.Fatalf("expected two ReplicaSets, got: %+v", got.Children)
+	}
+	first, second := got.Children[0].Children, got.Children[1].Children
+	if len(first) != 1 || first[0].Ref != "" || len(first[0].Children) != 1 || first[0].Children[0].Name != "shared-child" {
+		t.Fatalf("expected the full subtree of the shared pod under the first owner, got: %+v", first)
+	}
+	if len(second) != 1 || second[0].Ref != "Pod/shared" || len(second[0].Children) != 0 {
+		t.Fatalf("expected a reference to the shared pod under the second owner, got: %+v", second)
+	}
+	if owners := second[0].Owners; len(owners) != 2 || owners[0] != "ReplicaSet/app-1" || owners[1] != "ReplicaSet/app-2" {
+		t.Errorf("unexpected owners of the reference: %v", owners)
+	}
+}
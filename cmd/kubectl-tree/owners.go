@@ -0,0 +1,126 @@
+package main
+
+import (
+	"context"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/klog"
+)
+
+const (
+	notFoundReason    Reason = "NotFound"
+	forbiddenReason   Reason = "Forbidden"
+	uidMismatchReason Reason = "UIDMismatch"
+	unknownKindReason Reason = "UnknownKind"
+	errorReason       Reason = "Error"
+)
+
+// ownerResolver retrieves the owners of objects by following their ownerReferences.
+type ownerResolver struct {
+	client dynamic.Interface
+	mapper meta.RESTMapper
+
+	items      map[types.UID]unstructured.Unstructured
+	owners     map[types.UID]map[types.UID]bool
+	unresolved map[types.UID]Reason
+}
+
+// newOwnerDirectory walks ownerReferences upwards from the specified objects and returns a directory in which the owner
+// relationship is inverted (each object "owns" its owners), so that treeView prints the ancestor chain of each object.
+func newOwnerDirectory(client dynamic.Interface, mapper meta.RESTMapper, objs ...unstructured.Unstructured) objectDirectory {
+	r := &ownerResolver{
+		client:     client,
+		mapper:     mapper,
+		items:      make(map[types.UID]unstructured.Unstructured),
+		owners:     make(map[types.UID]map[types.UID]bool),
+		unresolved: make(map[types.UID]Reason),
+	}
+	for _, obj := range objs {
+		r.walk(obj)
+	}
+	return objectDirectory{
+		items:      r.items,
+		ownership:  r.owners,
+		unresolved: r.unresolved,
+	}
+}
+
+func (r *ownerResolver) walk(obj unstructured.Unstructured) {
+	if _, ok := r.items[obj.GetUID()]; ok {
+		return
+	}
+	r.items[obj.GetUID()] = obj
+	if _, ok := r.unresolved[obj.GetUID()]; ok {
+		return
+	}
+	for _, ref := range obj.GetOwnerReferences() {
+		if r.owners[obj.GetUID()] == nil {
+			r.owners[obj.GetUID()] = make(map[types.UID]bool)
+		}
+		r.owners[obj.GetUID()][ref.UID] = true
+
+		if _, ok := r.items[ref.UID]; ok {
+			continue
+		}
+		owner, reason := r.getOwner(obj.GetNamespace(), ref)
+		if reason != "" {
+			r.unresolved[ref.UID] = reason
+		}
+		r.walk(owner)
+	}
+}
+
+// getOwner fetches the object referred by ref from the API server. If the owner cannot be retrieved, it returns a
+// placeholder object and the reason.
+func (r *ownerResolver) getOwner(namespace string, ref metav1.OwnerReference) (unstructured.Unstructured, Reason) {
+	gv, err := schema.ParseGroupVersion(ref.APIVersion)
+	if err != nil {
+		klog.V(2).Infof("cannot parse apiVersion of owner %s/%s: %v", ref.Kind, ref.Name, err)
+		return placeholderObject(namespace, ref), unknownKindReason
+	}
+	mapping, err := r.mapper.RESTMapping(gv.WithKind(ref.Kind).GroupKind(), gv.Version)
+	if err != nil {
+		klog.V(2).Infof("cannot find API resource for owner kind %s: %v", gv.WithKind(ref.Kind), err)
+		return placeholderObject(namespace, ref), unknownKindReason
+	}
+	var ri dynamic.ResourceInterface
+	if mapping.Scope.Name() == meta.RESTScopeNameNamespace {
+		ri = r.client.Resource(mapping.Resource).Namespace(namespace)
+	} else {
+		namespace = ""
+		ri = r.client.Resource(mapping.Resource)
+	}
+	owner, err := ri.Get(context.TODO(), ref.Name, metav1.GetOptions{})
+	if err != nil {
+		klog.V(2).Infof("cannot get owner %s/%s (uid=%s): %v", ref.Kind, ref.Name, ref.UID, err)
+		switch {
+		case apierrors.IsNotFound(err):
+			return placeholderObject(namespace, ref), notFoundReason
+		case apierrors.IsForbidden(err):
+			return placeholderObject(namespace, ref), forbiddenReason
+		}
+		return placeholderObject(namespace, ref), errorReason
+	}
+	if owner.GetUID() != ref.UID {
+		klog.V(2).Infof("owner %s/%s has uid=%s, expected %s", ref.Kind, ref.Name, owner.GetUID(), ref.UID)
+		return placeholderObject(namespace, ref), uidMismatchReason
+	}
+	return *owner, ""
+}
+
+// placeholderObject builds an object that stands in for an owner that could not be retrieved.
+func placeholderObject(namespace string, ref metav1.OwnerReference) unstructured.Unstructured {
+	var obj unstructured.Unstructured
+	obj.SetAPIVersion(ref.APIVersion)
+	obj.SetKind(ref.Kind)
+	obj.SetName(ref.Name)
+	obj.SetNamespace(namespace)
+	obj.SetUID(ref.UID)
+	return obj
+}
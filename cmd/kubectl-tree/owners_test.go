@@ -0,0 +1,43 @@
+package main
+
+import (
+	"testing"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+)
+
+func TestNewOwnerDirectory(t *testing.T) {
+	mapper := meta.NewDefaultRESTMapper(nil)
+	mapper.Add(schema.GroupVersionKind{Group: "apps", Version: "v1", Kind: "Deployment"}, meta.RESTScopeNamespace)
+	mapper.Add(schema.GroupVersionKind{Group: "apps", Version: "v1", Kind: "ReplicaSet"}, meta.RESTScopeNamespace)
+
+	deploy := newTestObject("apps/v1", "Deployment", "app", "d1")
+	rs := newTestObject("apps/v1", "ReplicaSet", "app-1", "rs1")
+	rs.SetOwnerReferences([]metav1.OwnerReference{{APIVersion: "apps/v1", Kind: "Deployment", Name: "app", UID: "d1"}})
+	pod := newTestObject("v1", "Pod", "app-1-a", "p1")
+	pod.SetOwnerReferences([]metav1.OwnerReference{
+		{APIVersion: "apps/v1", Kind: "ReplicaSet", Name: "app-1", UID: "rs1"},
+		{APIVersion: "apps/v1", Kind: "ReplicaSet", Name: "app-0", UID: "gone"},
+	})
+
+	client := dynamicfake.NewSimpleDynamicClient(runtime.NewScheme(), &deploy, &rs)
+	objs := newOwnerDirectory(client, mapper, pod)
+
+	owners := objs.ownedBy("p1")
+	if len(owners) != 2 {
+		t.Fatalf("expected 2 owners of the pod, got %d", len(owners))
+	}
+	if owners[0].GetName() != "app-0" || objs.unresolved["gone"] != notFoundReason {
+		t.Fatalf("expected missing owner app-0 to be unresolved, got %s (%q)", owners[0].GetName(), objs.unresolved["gone"])
+	}
+	if got := objs.ownedBy("rs1"); len(got) != 1 || got[0].GetUID() != "d1" {
+		t.Fatalf("expected the deployment to own the replicaset, got %v", got)
+	}
+	if _, _, s := objs.statusOf(owners[0], nil); s != "NotFound" {
+		t.Fatalf("expected NotFound status for missing owner, got %q", s)
+	}
+}
@@ -4,28 +4,192 @@ import (
 	"context"
 	stderrors "errors"
 	"fmt"
+	"maps"
 	"sync"
 	"time"
 
 	"k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/cli-runtime/pkg/genericclioptions"
 	"k8s.io/client-go/dynamic"
 	"k8s.io/klog"
 )
 
-// getAllResources finds all API objects in specified API resources in all namespaces (or non-namespaced).
-func getAllResources(client dynamic.Interface, apis []apiResource, allNs bool, labelSelector string) ([]unstructured.Unstructured, error) {
+// treeQuery is what is queried from a cluster to build the trees of the roots specified with the KIND [NAME...]
+// arguments, or of the top-level owners with forest (--all).
+type treeQuery struct {
+	args   []string
+	forest bool
+	// rootRefs are the roots to query instead of the arguments, found by their kind, namespace and name (e.g. the
+	// roots of saved trees, which have different UIDs in the live cluster).
+	rootRefs      []unstructured.Unstructured
+	allNs         bool
+	labelSelector string
+	apiGroups     []string
+	resources     []string
+	includeOwners bool
+	refresh       bool
+	cacheTTL      time.Duration
+}
+
+// treeSource is a cluster prepared to query the objects in the trees of a treeQuery.
+type treeSource struct {
+	treeQuery
+	dyn dynamic.Interface
+
+	// all are all discovered API resources, regardless of the API groups and resources of the query.
+	all *resourceMap
+
+	// apis are the API resources to list. With includeOwners, the first list adds the API resources of the owners.
+	apis []apiResource
+
+	namespace string
+
+	// roots are the root objects resolved from the arguments, and single is whether a single object was specified by
+	// name. They are not set with forest or rootRefs, as the roots are found in the listed objects.
+	roots  []unstructured.Unstructured
+	single bool
+
+	ownersListed bool
+}
+
+// connect creates the clients for the cluster of the config flags, discovers its API resources and resolves the roots.
+func (q treeQuery) connect(f *genericclioptions.ConfigFlags) (*treeSource, error) {
+	dyn, dc, err := newClients(f, q.refresh, q.cacheTTL)
+	if err != nil {
+		return nil, err
+	}
+	s := &treeSource{treeQuery: q, dyn: dyn, namespace: queryNamespace(f, q.allNs)}
+	if s.all, err = findAPIs(dc, nil, nil); err != nil {
+		return nil, err
+	}
+	if len(q.rootRefs) > 0 {
+		if len(s.all.resources()) == 0 {
+			// the roots are only looked up in the listed objects, so they would all be missing otherwise
+			return nil, stderrors.New("failed to discover the API resources of the cluster")
+		}
+		s.namespace = refsNamespace(q.rootRefs)
+	} else if !q.forest {
+		if s.roots, s.single, err = resolveRoots(f, q.args, q.allNs, q.labelSelector); err != nil {
+			return nil, err
+		}
+	}
+	klog.V(2).Infof("namespace=%s allNamespaces=%v roots=%d", getNamespace(f), q.allNs, len(s.roots))
+	apis, err := findAPIs(dc, q.apiGroups, q.resources)
+	if err != nil {
+		return nil, err
+	}
+	klog.V(3).Info("completed querying APIs list")
+	s.apis = apis.resources()
+	return s, nil
+}
+
+// list lists the objects of the API resources, along with the API resources of their owners the first time with
+// includeOwners.
+func (s *treeSource) list() (*resourceList, error) {
+	if s.includeOwners && !s.ownersListed {
+		klog.V(2).Infof("querying api objects and the kinds of their owners")
+		listed, apis, err := getResourcesWithOwners(s.dyn, s.all, s.apis, s.namespace, s.labelSelector)
+		if err != nil {
+			return nil, fmt.Errorf("error while querying api objects: %w", err)
+		}
+		s.apis, s.ownersListed = apis, true
+		return listed, nil
+	}
+	klog.V(2).Infof("querying all api objects")
+	objs, versions, err := listAllResources(s.dyn, s.apis, s.namespace, s.labelSelector)
+	if err != nil {
+		return nil, fmt.Errorf("error while querying api objects: %w", err)
+	}
+	klog.V(2).Infof("found total %d api objects", len(objs))
+	return &resourceList{objects: objs, versions: versions}, nil
+}
+
+// treeRoots returns the roots of the trees in objs: the top-level owners with forest, the objects matching rootRefs,
+// or the latest state of the resolved roots.
+func (s *treeSource) treeRoots(objs objectDirectory) []unstructured.Unstructured {
+	switch {
+	case s.forest:
+		return objs.topLevelOwners("")
+	case len(s.rootRefs) > 0:
+		return findRefs(objs, s.rootRefs)
+	default:
+		return latestRoots(objs, s.roots)
+	}
+}
+
+// run queries the trees from the cluster of the config flags.
+func (q treeQuery) run(f *genericclioptions.ConfigFlags) (*treeSource, objectDirectory, []unstructured.Unstructured, error) {
+	s, err := q.connect(f)
+	if err != nil {
+		return nil, objectDirectory{}, nil, err
+	}
+	listed, err := s.list()
+	if err != nil {
+		return nil, objectDirectory{}, nil, err
+	}
+	objs := newObjectDirectory(listed.objects)
+	return s, objs, s.treeRoots(objs), nil
+}
+
+// refsNamespace returns the namespace of the objects if they are all in the same namespace, or "" to query all
+// namespaces.
+func refsNamespace(refs []unstructured.Unstructured) string {
+	ns := refs[0].GetNamespace()
+	for _, ref := range refs[1:] {
+		if ref.GetNamespace() != ns {
+			return ""
+		}
+	}
+	return ns
+}
+
+// findRefs returns the objects with the same kind, namespace and name as the refs, skipping the refs that are not
+// found.
+func findRefs(objs objectDirectory, refs []unstructured.Unstructured) []unstructured.Unstructured {
+	var out []unstructured.Unstructured
+	for _, ref := range refs {
+		for _, obj := range objs.items {
+			if obj.GroupVersionKind().GroupKind() == ref.GroupVersionKind().GroupKind() &&
+				obj.GetNamespace() == ref.GetNamespace() && obj.GetName() == ref.GetName() {
+				out = append(out, obj)
+				break
+			}
+		}
+	}
+	return out
+}
+
+// getAllResources finds all API objects in specified API resources in the namespace, or in all namespaces (including
+// the non-namespaced objects) if it's empty.
+func getAllResources(client dynamic.Interface, apis []apiResource, namespace string, labelSelector string) ([]unstructured.Unstructured, error) {
+	out, _, err := listAllResources(client, apis, namespace, labelSelector)
+	return out, err
+}
+
+// resourceList is the result of listing API resources: the objects, and the resource version of the list of each API
+// resource, which can be used to start watches from.
+type resourceList struct {
+	objects  []unstructured.Unstructured
+	versions map[schema.GroupVersionResource]string
+}
+
+// listAllResources is like getAllResources, but also returns the resource version of the list of each queried API
+// resource, which can be used to start watches from.
+func listAllResources(client dynamic.Interface, apis []apiResource, namespace string, labelSelector string) ([]unstructured.Unstructured, map[schema.GroupVersionResource]string, error) {
 	var mu sync.Mutex
 	var wg sync.WaitGroup
 	var out []unstructured.Unstructured
+	versions := make(map[schema.GroupVersionResource]string)
 
 	start := time.Now()
 	klog.V(2).Infof("starting to query %d APIs in concurrently", len(apis))
 
 	var errResult error
 	for _, api := range apis {
-		if !allNs && !api.r.Namespaced {
+		if namespace != "" && !api.r.Namespaced {
 			klog.V(4).Infof("[query api] api (%s) is non-namespaced, skipping", api.r.Name)
 			continue
 		}
@@ -33,7 +197,7 @@ func getAllResources(client dynamic.Interface, apis []apiResource, allNs bool, l
 		go func(a apiResource) {
 			defer wg.Done()
 			klog.V(4).Infof("[query api] start: %s", a.GroupVersionResource())
-			v, err := queryAPI(client, a, allNs, labelSelector)
+			v, rv, err := queryAPI(client, a, namespace, labelSelector)
 			if err != nil {
 				if errors.IsForbidden(err) {
 					// should not fail the overall process, but print an info message indicating the permission issue
@@ -41,12 +205,15 @@ func getAllResources(client dynamic.Interface, apis []apiResource, allNs bool, l
 					klog.Infof("cannot query %s (forbidden), omitting from the tree", a.GroupVersionResource().GroupResource())
 				} else {
 					klog.V(4).Infof("[query api] error querying: %s, error=%v", a.GroupVersionResource(), err)
+					mu.Lock()
 					errResult = stderrors.Join(errResult, fmt.Errorf("failed to query the %s resources: %w", a.GroupVersionResource(), err))
+					mu.Unlock()
 				}
 				return
 			}
 			mu.Lock()
 			out = append(out, v...)
+			versions[a.GroupVersionResource()] = rv
 			mu.Unlock()
 			klog.V(4).Infof("[query api]  done: %s, found %d apis", a.GroupVersionResource(), len(v))
 		}(api)
@@ -56,23 +223,64 @@ func getAllResources(client dynamic.Interface, apis []apiResource, allNs bool, l
 	wg.Wait()
 	klog.V(2).Infof("all goroutines have returned in %v", time.Since(start))
 	klog.V(2).Infof("query result: error=%v, objects=%d", errResult, len(out))
-	return out, errResult
+	return out, versions, errResult
 }
 
-func queryAPI(client dynamic.Interface, api apiResource, allNs bool, labelSelector string) ([]unstructured.Unstructured, error) {
+// getResourcesWithOwners is like getAllResources, but it also queries the API resources of the owners of the found
+// objects (looked up in all by the apiVersion and kind of their ownerReferences), and then of their owners, and so on,
+// so that filtering the API resources doesn't break the chain to the root objects. It returns the listed objects and all
+// of the queried API resources.
+func getResourcesWithOwners(client dynamic.Interface, all *resourceMap, apis []apiResource, namespace string, labelSelector string) (*resourceList, []apiResource, error) {
+	out := &resourceList{versions: make(map[schema.GroupVersionResource]string)}
+	var queried []apiResource
+	seen := make(map[schema.GroupKind]bool)
+	for _, api := range apis {
+		seen[schema.GroupKind{Group: api.gv.Group, Kind: api.r.Kind}] = true
+	}
+	for next := apis; len(next) > 0; {
+		objs, versions, err := listAllResources(client, next, namespace, labelSelector)
+		if err != nil {
+			return nil, nil, err
+		}
+		out.objects = append(out.objects, objs...)
+		maps.Copy(out.versions, versions)
+		queried = append(queried, next...)
+
+		next = nil
+		for _, obj := range objs {
+			for _, ref := range obj.GetOwnerReferences() {
+				gv, err := schema.ParseGroupVersion(ref.APIVersion)
+				if err != nil {
+					klog.V(3).Infof("invalid apiVersion in ownerReference of %s/%s: %v", obj.GetKind(), obj.GetName(), err)
+					continue
+				}
+				gk := schema.GroupKind{Group: gv.Group, Kind: ref.Kind}
+				if seen[gk] {
+					continue
+				}
+				seen[gk] = true
+				api, ok := all.lookupKind(gk)
+				if !ok {
+					klog.V(2).Infof("owner kind %s of %s/%s is not a listable API resource", gk, obj.GetKind(), obj.GetName())
+					continue
+				}
+				klog.V(2).Infof("including %s in the query, as the owner kind of %s/%s", api.GroupVersionResource(), obj.GetKind(), obj.GetName())
+				next = append(next, api)
+			}
+		}
+	}
+	return out, queried, nil
+}
+
+func queryAPI(client dynamic.Interface, api apiResource, namespace string, labelSelector string) ([]unstructured.Unstructured, string, error) {
 	var out []unstructured.Unstructured
 
 	var next string
-	var ns string
-
-	if !allNs {
-		ns = getNamespace()
-	}
 	for {
 		var intf dynamic.ResourceInterface
 		nintf := client.Resource(api.GroupVersionResource())
-		if !allNs {
-			intf = nintf.Namespace(ns)
+		if namespace != "" {
+			intf = nintf.Namespace(namespace)
 		} else {
 			intf = nintf
 		}
@@ -85,14 +293,13 @@ func queryAPI(client dynamic.Interface, api apiResource, allNs bool, labelSelect
 		}
 		resp, err := intf.List(context.TODO(), listOptions)
 		if err != nil {
-			return nil, fmt.Errorf("listing resources failed (%s): %w", api.GroupVersionResource(), err)
+			return nil, "", fmt.Errorf("listing resources failed (%s): %w", api.GroupVersionResource(), err)
 		}
 		out = append(out, resp.Items...)
 
 		next = resp.GetContinue()
 		if next == "" {
-			break
+			return out, resp.GetResourceVersion(), nil
 		}
 	}
-	return out, nil
 }
@@ -0,0 +1,248 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	stderrors "errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/fatih/color"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+	k8stesting "k8s.io/client-go/testing"
+	"k8s.io/utils/ptr"
+)
+
+func TestGetResourcesWithOwners(t *testing.T) {
+	newAPI := func(group, resource, kind string) apiResource {
+		return apiResource{
+			gv: schema.GroupVersion{Group: group, Version: "v1"},
+			r:  metav1.APIResource{Name: resource, Kind: kind, Namespaced: true, Verbs: []string{"list"}},
+		}
+	}
+	deployments := newAPI("apps", "deployments", "Deployment")
+	replicaSets := newAPI("apps", "replicasets", "ReplicaSet")
+	pods := newAPI("", "pods", "Pod")
+	configMaps := newAPI("", "configmaps", "ConfigMap")
+	all := &resourceMap{list: []apiResource{deployments, replicaSets, pods, configMaps}}
+
+	deploy := newTestObject("apps/v1", "Deployment", "app", "d1")
+	rs := newTestObject("apps/v1", "ReplicaSet", "app-1", "rs1")
+	rs.SetOwnerReferences([]metav1.OwnerReference{{APIVersion: "apps/v1", Kind: "Deployment", Name: "app", UID: "d1"}})
+	pod := newTestObject("v1", "Pod", "app-1-a", "p1")
+	pod.SetOwnerReferences([]metav1.OwnerReference{{APIVersion: "apps/v1", Kind: "ReplicaSet", Name: "app-1", UID: "rs1"}})
+	cm := newTestObject("v1", "ConfigMap", "app-config", "cm1")
+
+	client := dynamicfake.NewSimpleDynamicClientWithCustomListKinds(runtime.NewScheme(), map[schema.GroupVersionResource]string{
+		deployments.GroupVersionResource(): "DeploymentList",
+		replicaSets.GroupVersionResource(): "ReplicaSetList",
+		pods.GroupVersionResource():        "PodList",
+		configMaps.GroupVersionResource():  "ConfigMapList",
+	}, &deploy, &rs, &pod, &cm)
+
+	listed, queried, err := getResourcesWithOwners(client, all, []apiResource{pods}, "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names, resources []string
+	for _, obj := range listed.objects {
+		names = append(names, obj.GetName())
+	}
+	for _, api := range queried {
+		resources = append(resources, api.r.Name)
+	}
+	if want := []string{"app-1-a", "app-1", "app"}; !slices.Equal(names, want) {
+		t.Errorf("got objects %v, want %v", names, want)
+	}
+	if want := []string{"pods", "replicasets", "deployments"}; !slices.Equal(resources, want) {
+		t.Errorf("got queried resources %v, want %v", resources, want)
+	}
+	for _, api := range queried {
+		if _, ok := listed.versions[api.GroupVersionResource()]; !ok {
+			t.Errorf("no resource version for %s", api.GroupVersionResource())
+		}
+	}
+}
+
+func TestListAllResourcesErrors(t *testing.T) {
+	newAPI := func(resource, kind string) apiResource {
+		return apiResource{
+			gv: schema.GroupVersion{Version: "v1"},
+			r:  metav1.APIResource{Name: resource, Kind: kind, Namespaced: true, Verbs: []string{"list"}},
+		}
+	}
+	pods, configMaps, secrets := newAPI("pods", "Pod"), newAPI("configmaps", "ConfigMap"), newAPI("secrets", "Secret")
+	client := dynamicfake.NewSimpleDynamicClientWithCustomListKinds(runtime.NewScheme(), map[schema.GroupVersionResource]string{
+		pods.GroupVersionResource():       "PodList",
+		configMaps.GroupVersionResource(): "ConfigMapList",
+		secrets.GroupVersionResource():    "SecretList",
+	})
+	client.PrependReactor("list", "*", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		switch action.GetResource().Resource {
+		case "secrets":
+			return true, nil, apierrors.NewForbidden(action.GetResource().GroupResource(), "", stderrors.New("denied"))
+		case "pods", "configmaps":
+			return true, nil, apierrors.NewInternalError(stderrors.New("unavailable"))
+		}
+		return false, nil, nil
+	})
+
+	// the errors of all APIs are returned, except for the forbidden ones that are omitted from the tree
+	_, _, err := listAllResources(client, []apiResource{pods, configMaps, secrets}, "default", "")
+	if err == nil {
+		t.Fatal("got no error")
+	}
+	for _, resource := range []string{"pods", "configmaps"} {
+		if !strings.Contains(err.Error(), "/v1, Resource="+resource) {
+			t.Errorf("error does not mention %s: %v", resource, err)
+		}
+	}
+	if strings.Contains(err.Error(), "secrets") {
+		t.Errorf("error mentions forbidden secrets: %v", err)
+	}
+}
+
+// newFakeAPIServer starts an API server that serves the discovery of Deployments, ReplicaSets and Pods, and lists
+// and gets the objects in the default namespace. It returns a kubeconfig file for it.
+func newFakeAPIServer(t *testing.T, objs ...unstructured.Unstructured) string {
+	t.Helper()
+	namespaced := func(name, kind string) metav1.APIResource {
+		return metav1.APIResource{Name: name, Kind: kind, Namespaced: true, Verbs: metav1.Verbs{"get", "list", "watch"}}
+	}
+	apps := metav1.GroupVersionForDiscovery{GroupVersion: "apps/v1", Version: "v1"}
+	responses := map[string]interface{}{
+		"/api":    metav1.APIVersions{Versions: []string{"v1"}},
+		"/apis":   metav1.APIGroupList{Groups: []metav1.APIGroup{{Name: "apps", Versions: []metav1.GroupVersionForDiscovery{apps}, PreferredVersion: apps}}},
+		"/api/v1": metav1.APIResourceList{GroupVersion: "v1", APIResources: []metav1.APIResource{namespaced("pods", "Pod")}},
+		"/apis/apps/v1": metav1.APIResourceList{GroupVersion: "apps/v1", APIResources: []metav1.APIResource{
+			namespaced("deployments", "Deployment"), namespaced("replicasets", "ReplicaSet"),
+		}},
+	}
+	lists := map[string]string{
+		"/api/v1/namespaces/default/pods":              "Pod",
+		"/apis/apps/v1/namespaces/default/deployments": "Deployment",
+		"/apis/apps/v1/namespaces/default/replicasets": "ReplicaSet",
+	}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body := responses[r.URL.Path]
+		if kind, ok := lists[path.Dir(r.URL.Path)]; ok {
+			for _, obj := range objs {
+				if obj.GetKind() == kind && obj.GetName() == path.Base(r.URL.Path) {
+					body = obj.Object
+				}
+			}
+		}
+		if kind, isList := lists[r.URL.Path]; isList {
+			var items []interface{}
+			for _, obj := range objs {
+				if obj.GetKind() == kind {
+					items = append(items, obj.Object)
+				}
+			}
+			body = map[string]interface{}{"kind": kind + "List", "metadata": map[string]interface{}{"resourceVersion": "1"}, "items": items}
+		}
+		if body == nil {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(body)
+	}))
+	t.Cleanup(srv.Close)
+
+	kubeconfig := filepath.Join(t.TempDir(), "config")
+	if err := os.WriteFile(kubeconfig, []byte(fmt.Sprintf(`apiVersion: v1
+kind: Config
+clusters: [{name: test, cluster: {server: %q}}]
+contexts: [{name: test, context: {cluster: test, namespace: default}}]
+current-context: test
+`, srv.URL)), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return kubeconfig
+}
+
+// runCommand runs the command line with the kubeconfig and returns its output.
+func runCommand(t *testing.T, kubeconfig string, args ...string) string {
+	t.Helper()
+	defer func(w io.Writer) { color.Output = w }(color.Output)
+	var buf bytes.Buffer
+	color.Output = &buf
+	// the kubeconfig flags cache the loaded kubeconfig, so they are replaced rather than set on the command line
+	defer func(f *genericclioptions.ConfigFlags) { cf = f }(cf)
+	cf = genericclioptions.NewConfigFlags(true)
+	cf.KubeConfig, cf.CacheDir = ptr.To(kubeconfig), ptr.To(t.TempDir())
+	defer resetFlags(rootCmd)
+	rootCmd.SetArgs(args)
+	if err := rootCmd.Execute(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.String()
+}
+
+// resetFlags sets the flags of the command and its subcommands that were set on the command line back to their
+// defaults, since the flags keep their values between the runs of the command.
+func resetFlags(cmd *cobra.Command) {
+	for _, c := range append(cmd.Commands(), cmd) {
+		c.Flags().VisitAll(func(f *pflag.Flag) {
+			if !f.Changed {
+				return
+			}
+			if v, ok := f.Value.(pflag.SliceValue); ok {
+				var def []string
+				if s := strings.Trim(f.DefValue, "[]"); s != "" {
+					def = strings.Split(s, ",")
+				}
+				_ = v.Replace(def)
+			} else {
+				_ = f.Value.Set(f.DefValue)
+			}
+			f.Changed = false
+		})
+	}
+}
+
+func TestTreeQueryContexts(t *testing.T) {
+	defer func(v bool) { color.NoColor = v }(color.NoColor)
+	color.NoColor = true
+
+	ownedBy := func(obj, owner unstructured.Unstructured) unstructured.Unstructured {
+		obj.SetOwnerReferences([]metav1.OwnerReference{{APIVersion: owner.GetAPIVersion(), Kind: owner.GetKind(), Name: owner.GetName(), UID: owner.GetUID()}})
+		return obj
+	}
+	deploy := newTestObject("apps/v1", "Deployment", "app", "d1")
+	rs := ownedBy(newTestObject("apps/v1", "ReplicaSet", "app-1", "rs1"), deploy)
+	kubeconfig := newFakeAPIServer(t,
+		deploy,
+		rs,
+		ownedBy(newTestObject("v1", "Pod", "app-1-a", "p1"), rs),
+		newTestObject("apps/v1", "Deployment", "other", "d2"),
+	)
+	// the trees queried from the current context and with --contexts are the same, here with the owners of the Pods
+	// queried through --include-owners
+	want := runCommand(t, kubeconfig, "deployments", "app", "--resources", "pods", "--include-owners")
+	if got := runCommand(t, kubeconfig, "deployments", "app", "--resources", "pods", "--include-owners", "--contexts", "test"); got != "Cluster: test\n"+want {
+		t.Errorf("got with --contexts:\n%s\nwant:\nCluster: test\n%s", got, want)
+	}
+	for _, name := range []string{"Deployment/app", "└─ReplicaSet/app-1", "└─Pod/app-1-a"} {
+		if !strings.Contains(want, name) {
+			t.Errorf("output does not contain %q:\n%s", name, want)
+		}
+	}
+}
@@ -3,14 +3,22 @@ package main
 import (
 	"sort"
 
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/cli-utils/pkg/kstatus/status"
 )
 
 // objectDirectory stores objects and owner relationships between them.
 type objectDirectory struct {
 	items     map[types.UID]unstructured.Unstructured
 	ownership map[types.UID]map[types.UID]bool
+
+	// relations holds logical relationships that are not expressed through ownerReferences, by their type.
+	relations map[types.UID]map[types.UID]string
+
+	// unresolved holds placeholder objects that are referenced but could not be retrieved, with the reason why.
+	unresolved map[types.UID]Reason
 }
 
 // newObjectDirectory builds object lookup and hierarchy.
@@ -18,22 +26,59 @@ func newObjectDirectory(objs []unstructured.Unstructured) objectDirectory {
 	v := objectDirectory{
 		items:     make(map[types.UID]unstructured.Unstructured),
 		ownership: make(map[types.UID]map[types.UID]bool),
+		relations: make(map[types.UID]map[types.UID]string),
 	}
 	for _, obj := range objs {
-		v.items[obj.GetUID()] = obj
-		for _, ownerRef := range obj.GetOwnerReferences() {
-			if v.ownership[ownerRef.UID] == nil {
-				v.ownership[ownerRef.UID] = make(map[types.UID]bool)
-			}
-			v.ownership[ownerRef.UID][obj.GetUID()] = true
-		}
+		v.upsert(obj)
 	}
 	return v
 }
 
+// upsert adds obj to the directory, or replaces the existing object with the same id and updates its owners.
+func (od objectDirectory) upsert(obj unstructured.Unstructured) {
+	if old, ok := od.items[obj.GetUID()]; ok {
+		od.removeOwners(old)
+	}
+	od.items[obj.GetUID()] = obj
+	for _, ownerRef := range obj.GetOwnerReferences() {
+		if od.ownership[ownerRef.UID] == nil {
+			od.ownership[ownerRef.UID] = make(map[types.UID]bool)
+		}
+		od.ownership[ownerRef.UID][obj.GetUID()] = true
+	}
+}
+
+// remove deletes obj from the directory, objects owned by it are kept.
+func (od objectDirectory) remove(obj unstructured.Unstructured) {
+	if old, ok := od.items[obj.GetUID()]; ok {
+		od.removeOwners(old)
+	}
+	delete(od.items, obj.GetUID())
+}
+
+func (od objectDirectory) removeOwners(obj unstructured.Unstructured) {
+	for _, ownerRef := range obj.GetOwnerReferences() {
+		delete(od.ownership[ownerRef.UID], obj.GetUID())
+		if len(od.ownership[ownerRef.UID]) == 0 {
+			delete(od.ownership, ownerRef.UID)
+		}
+	}
+}
+
 // getObject finds object by ID, since objectDirectory is built with specified objects, id should exist in there.
 func (od objectDirectory) getObject(id types.UID) unstructured.Unstructured { return od.items[id] }
 
+// statusOf returns the ready status, reason and kstatus of obj, or the reason it could not be retrieved if it's a placeholder.
+func (od objectDirectory) statusOf(obj unstructured.Unstructured, conditionTypes []string) (ReadyStatus, Reason, status.Status) {
+	if reason, ok := od.unresolved[obj.GetUID()]; ok {
+		if reason == notFoundReason {
+			return "", reason, status.NotFoundStatus
+		}
+		return "", reason, status.UnknownStatus
+	}
+	return extractStatus(obj, conditionTypes)
+}
+
 // ownedBy returns objects directly owned by specified id, sorted by Kind, then by Name, then by Namespace.
 func (od objectDirectory) ownedBy(id types.UID) []unstructured.Unstructured {
 	var out sortedObjects
@@ -44,6 +89,70 @@ func (od objectDirectory) ownedBy(id types.UID) []unstructured.Unstructured {
 	return out
 }
 
+// treeChild is an object shown under another object in the tree.
+type treeChild struct {
+	unstructured.Unstructured
+
+	// relation is the type of the logical relationship with the parent, or empty if the child is owned by the parent.
+	relation string
+
+	// ownerRef is the ownerReference between the child and the parent (in either direction, since the owners are the
+	// children in inverted trees), or nil if it's not found.
+	ownerRef *metav1.OwnerReference
+}
+
+// children returns objects owned by specified id followed by the objects related to it through logical relationships,
+// each group sorted by Kind, then by Name, then by Namespace.
+func (od objectDirectory) children(id types.UID) []treeChild {
+	var out []treeChild
+	for _, obj := range od.ownedBy(id) {
+		out = append(out, treeChild{Unstructured: obj, ownerRef: od.ownerRef(id, obj)})
+	}
+	var related sortedObjects
+	for k := range od.relations[id] {
+		related = append(related, od.getObject(k))
+	}
+	sort.Sort(related)
+	for _, obj := range related {
+		out = append(out, treeChild{Unstructured: obj, relation: od.relations[id][obj.GetUID()]})
+	}
+	return out
+}
+
+// topLevelOwners returns the objects (in the specified namespace, or all namespaces if empty) that have no owners but
+// own other objects, sorted by namespace, kind and name.
+func (od objectDirectory) topLevelOwners(namespace string) []unstructured.Unstructured {
+	var out []unstructured.Unstructured
+	for id, obj := range od.items {
+		if len(obj.GetOwnerReferences()) > 0 || len(od.ownership[id]) == 0 {
+			continue
+		}
+		if namespace != "" && obj.GetNamespace() != namespace {
+			continue
+		}
+		out = append(out, obj)
+	}
+	sortObjects(out)
+	return out
+}
+
+// ownerRef returns the ownerReference of child to the object with the specified id, or the ownerReference of that
+// object to child.
+func (od objectDirectory) ownerRef(id types.UID, child unstructured.Unstructured) *metav1.OwnerReference {
+	for _, ref := range child.GetOwnerReferences() {
+		if ref.UID == id {
+			return &ref
+		}
+	}
+	parent := od.items[id]
+	for _, ref := range parent.GetOwnerReferences() {
+		if ref.UID == child.GetUID() {
+			return &ref
+		}
+	}
+	return nil
+}
+
 // sortedObjects sorts objects by Kind, then by Name, then by Namespace.
 type sortedObjects []unstructured.Unstructured
 
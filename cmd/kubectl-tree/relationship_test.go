@@ -0,0 +1,44 @@
+package main
+
+import (
+	"testing"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+func TestTopLevelOwners(t *testing.T) {
+	other := newTestObject("apps/v1", "StatefulSet", "db", "ss1")
+	other.SetNamespace("other")
+	objs := newObjectDirectory([]unstructured.Unstructured{
+		newTestObject("apps/v1", "Deployment", "app", "d1"),
+		newTestObject("apps/v1", "ReplicaSet", "app-1", "rs1", "d1"),
+		newTestObject("v1", "Pod", "app-1-a", "p1", "rs1"),
+		newTestObject("v1", "ConfigMap", "unowned", "cm1"),
+		newTestObject("v1", "Pod", "orphan", "p2", "rs-gone"),
+		other,
+		newTestObject("v1", "Pod", "db-0", "p3", "ss1"),
+	})
+
+	tests := []struct {
+		namespace string
+		want      []string
+	}{
+		{namespace: "", want: []string{"app", "db"}},
+		{namespace: "default", want: []string{"app"}},
+		{namespace: "missing", want: nil},
+	}
+	for _, tt := range tests {
+		var got []string
+		for _, obj := range objs.topLevelOwners(tt.namespace) {
+			got = append(got, obj.GetName())
+		}
+		if len(got) != len(tt.want) {
+			t.Fatalf("topLevelOwners(%q) = %v, want %v", tt.namespace, got, tt.want)
+		}
+		for i := range got {
+			if got[i] != tt.want[i] {
+				t.Fatalf("topLevelOwners(%q) = %v, want %v", tt.namespace, got, tt.want)
+			}
+		}
+	}
+}
@@ -0,0 +1,261 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+// relation is a logical relationship between two objects that is not expressed through ownerReferences, such as a
+// Pod using a ConfigMap. In the tree, the "to" object is shown under the "from" object.
+type relation struct {
+	from, to types.UID
+	typ      string
+}
+
+// relationshipResolver finds logical relationships among the objects in the directory.
+type relationshipResolver func(objs objectDirectory, idx objectIndex) []relation
+
+// relationshipResolvers are the available resolvers, by the name used in the --relations flag.
+var relationshipResolvers = map[string]relationshipResolver{
+	"services":    resolveServiceEndpoints,
+	"volumes":     resolveVolumes,
+	"config":      resolvePodConfig,
+	"ingresses":   resolveIngressBackends,
+	"autoscalers": resolveScaleTargets,
+}
+
+// allRelations is the --relations value that enables all resolvers.
+const allRelations = "all"
+
+// relationshipResolverNames returns the sorted names of available resolvers.
+func relationshipResolverNames() []string {
+	var out []string
+	for k := range relationshipResolvers {
+		out = append(out, k)
+	}
+	sort.Strings(out)
+	return out
+}
+
+// validateRelations checks that all specified relationship resolver names exist.
+func validateRelations(names []string) error {
+	for _, n := range names {
+		if _, ok := relationshipResolvers[n]; !ok && n != allRelations {
+			return fmt.Errorf("unknown relation %q (supported: %s, %s)", n, strings.Join(relationshipResolverNames(), ", "), allRelations)
+		}
+	}
+	return nil
+}
+
+// resolveRelations replaces the logical relationships in the directory with the ones found by specified resolvers.
+// Relationships that duplicate an ownerReference are omitted.
+func (od objectDirectory) resolveRelations(names []string) {
+	clear(od.relations)
+	if len(names) == 0 {
+		return
+	}
+	if contains(names, allRelations) {
+		names = relationshipResolverNames()
+	}
+	idx := newObjectIndex(od)
+	for _, n := range names {
+		for _, r := range relationshipResolvers[n](od, idx) {
+			if r.from == r.to || od.ownership[r.from][r.to] {
+				continue
+			}
+			if od.relations[r.from] == nil {
+				od.relations[r.from] = make(map[types.UID]string)
+			}
+			od.relations[r.from][r.to] = r.typ
+		}
+	}
+}
+
+// objectKey identifies an object by its group, kind, namespace and name.
+type objectKey struct {
+	gk        schema.GroupKind
+	namespace string
+	name      string
+}
+
+// objectIndex finds objects in a directory by group, kind, namespace and name.
+type objectIndex map[objectKey]types.UID
+
+func newObjectIndex(od objectDirectory) objectIndex {
+	idx := make(objectIndex)
+	for id, obj := range od.items {
+		idx[objectKey{obj.GroupVersionKind().GroupKind(), obj.GetNamespace(), obj.GetName()}] = id
+	}
+	return idx
+}
+
+func (idx objectIndex) find(group, kind, namespace, name string) (types.UID, bool) {
+	id, ok := idx[objectKey{schema.GroupKind{Group: group, Kind: kind}, namespace, name}]
+	return id, ok
+}
+
+// objectsOfKind returns the objects in the directory with specified group and kind.
+func objectsOfKind(od objectDirectory, group, kind string) []unstructured.Unstructured {
+	var out []unstructured.Unstructured
+	for _, obj := range od.items {
+		gk := obj.GroupVersionKind().GroupKind()
+		if gk.Group == group && gk.Kind == kind {
+			out = append(out, obj)
+		}
+	}
+	return out
+}
+
+// resolveServiceEndpoints links Services to their Endpoints and EndpointSlices.
+func resolveServiceEndpoints(od objectDirectory, idx objectIndex) []relation {
+	var out []relation
+	for _, svc := range objectsOfKind(od, "", "Service") {
+		if id, ok := idx.find("", "Endpoints", svc.GetNamespace(), svc.GetName()); ok {
+			out = append(out, relation{svc.GetUID(), id, "endpoints"})
+		}
+	}
+	for _, es := range objectsOfKind(od, "discovery.k8s.io", "EndpointSlice") {
+		svcName := es.GetLabels()["kubernetes.io/service-name"]
+		if id, ok := idx.find("", "Service", es.GetNamespace(), svcName); ok && svcName != "" {
+			out = append(out, relation{id, es.GetUID(), "endpoints"})
+		}
+	}
+	return out
+}
+
+// resolveVolumes links Pods to the PersistentVolumeClaims they mount, and PersistentVolumeClaims to the
+// PersistentVolumes they are bound to.
+func resolveVolumes(od objectDirectory, idx objectIndex) []relation {
+	var out []relation
+	for _, pod := range objectsOfKind(od, "", "Pod") {
+		volumes, _, _ := unstructured.NestedSlice(pod.Object, "spec", "volumes")
+		for _, v := range volumes {
+			claim := nestedString(v, "persistentVolumeClaim", "claimName")
+			if id, ok := idx.find("", "PersistentVolumeClaim", pod.GetNamespace(), claim); ok {
+				out = append(out, relation{pod.GetUID(), id, "mounts"})
+			}
+		}
+	}
+	for _, pvc := range objectsOfKind(od, "", "PersistentVolumeClaim") {
+		pv, _, _ := unstructured.NestedString(pvc.Object, "spec", "volumeName")
+		if id, ok := idx.find("", "PersistentVolume", "", pv); ok {
+			out = append(out, relation{pvc.GetUID(), id, "binds"})
+		}
+	}
+	return out
+}
+
+// resolvePodConfig links Pods to the ConfigMaps and Secrets they reference in volumes, environment variables and
+// image pull secrets, and to their ServiceAccount.
+func resolvePodConfig(od objectDirectory, idx objectIndex) []relation {
+	var out []relation
+	for _, pod := range objectsOfKind(od, "", "Pod") {
+		ns := pod.GetNamespace()
+		link := func(kind, name string) {
+			if id, ok := idx.find("", kind, ns, name); ok {
+				out = append(out, relation{pod.GetUID(), id, "uses"})
+			}
+		}
+
+		volumes, _, _ := unstructured.NestedSlice(pod.Object, "spec", "volumes")
+		for _, v := range volumes {
+			link("ConfigMap", nestedString(v, "configMap", "name"))
+			link("Secret", nestedString(v, "secret", "secretName"))
+			sources, _, _ := unstructured.NestedSlice(asMap(v), "projected", "sources")
+			for _, src := range sources {
+				link("ConfigMap", nestedString(src, "configMap", "name"))
+				link("Secret", nestedString(src, "secret", "name"))
+			}
+		}
+		for _, field := range []string{"initContainers", "containers", "ephemeralContainers"} {
+			containers, _, _ := unstructured.NestedSlice(pod.Object, "spec", field)
+			for _, c := range containers {
+				env, _, _ := unstructured.NestedSlice(asMap(c), "env")
+				for _, e := range env {
+					link("ConfigMap", nestedString(e, "valueFrom", "configMapKeyRef", "name"))
+					link("Secret", nestedString(e, "valueFrom", "secretKeyRef", "name"))
+				}
+				envFrom, _, _ := unstructured.NestedSlice(asMap(c), "envFrom")
+				for _, e := range envFrom {
+					link("ConfigMap", nestedString(e, "configMapRef", "name"))
+					link("Secret", nestedString(e, "secretRef", "name"))
+				}
+			}
+		}
+		pullSecrets, _, _ := unstructured.NestedSlice(pod.Object, "spec", "imagePullSecrets")
+		for _, s := range pullSecrets {
+			link("Secret", nestedString(s, "name"))
+		}
+		sa, _, _ := unstructured.NestedString(pod.Object, "spec", "serviceAccountName")
+		link("ServiceAccount", sa)
+	}
+	return out
+}
+
+// resolveIngressBackends links Ingresses to the Services they route traffic to.
+func resolveIngressBackends(od objectDirectory, idx objectIndex) []relation {
+	var out []relation
+	for _, group := range []string{"networking.k8s.io", "extensions"} {
+		for _, ing := range objectsOfKind(od, group, "Ingress") {
+			backends := []any{
+				asMap(ing.Object["spec"])["defaultBackend"],
+				asMap(ing.Object["spec"])["backend"],
+			}
+			rules, _, _ := unstructured.NestedSlice(ing.Object, "spec", "rules")
+			for _, r := range rules {
+				paths, _, _ := unstructured.NestedSlice(asMap(r), "http", "paths")
+				for _, p := range paths {
+					backends = append(backends, asMap(p)["backend"])
+				}
+			}
+			for _, b := range backends {
+				name := nestedString(b, "service", "name")
+				if name == "" {
+					name = nestedString(b, "serviceName") // extensions/v1beta1
+				}
+				if id, ok := idx.find("", "Service", ing.GetNamespace(), name); ok {
+					out = append(out, relation{ing.GetUID(), id, "routes"})
+				}
+			}
+		}
+	}
+	return out
+}
+
+// resolveScaleTargets links HorizontalPodAutoscalers to the objects they scale.
+func resolveScaleTargets(od objectDirectory, idx objectIndex) []relation {
+	var out []relation
+	for _, hpa := range objectsOfKind(od, "autoscaling", "HorizontalPodAutoscaler") {
+		ref, _, _ := unstructured.NestedMap(hpa.Object, "spec", "scaleTargetRef")
+		gv, err := schema.ParseGroupVersion(nestedString(ref, "apiVersion"))
+		if err != nil {
+			continue
+		}
+		if id, ok := idx.find(gv.Group, nestedString(ref, "kind"), hpa.GetNamespace(), nestedString(ref, "name")); ok {
+			out = append(out, relation{hpa.GetUID(), id, "scales"})
+		}
+	}
+	return out
+}
+
+func asMap(v any) map[string]any {
+	m, _ := v.(map[string]any)
+	return m
+}
+
+// nestedString returns the string at the specified path in v, or an empty string if it doesn't exist.
+func nestedString(v any, fields ...string) string {
+	s, _, _ := unstructured.NestedString(asMap(v), fields...)
+	return s
+}
+
+// nestedInt64 returns the integer at the specified path in v, or 0 if it doesn't exist.
+func nestedInt64(v any, fields ...string) int64 {
+	i, _, _ := unstructured.NestedInt64(asMap(v), fields...)
+	return i
+}
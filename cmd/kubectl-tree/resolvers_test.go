@@ -0,0 +1,74 @@
+package main
+
+import (
+	"testing"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+func TestResolveRelations(t *testing.T) {
+	pod := newTestObject("v1", "Pod", "app", "p1")
+	pod.Object["spec"] = map[string]interface{}{
+		"serviceAccountName": "app-sa",
+		"volumes": []interface{}{
+			map[string]interface{}{"name": "data", "persistentVolumeClaim": map[string]interface{}{"claimName": "data"}},
+			map[string]interface{}{"name": "config", "configMap": map[string]interface{}{"name": "app-config"}},
+		},
+		"containers": []interface{}{
+			map[string]interface{}{
+				"name":    "app",
+				"envFrom": []interface{}{map[string]interface{}{"secretRef": map[string]interface{}{"name": "app-secret"}}},
+			},
+		},
+	}
+	pvc := newTestObject("v1", "PersistentVolumeClaim", "data", "pvc1")
+	pvc.Object["spec"] = map[string]interface{}{"volumeName": "pv-1"}
+	pv := newTestObject("v1", "PersistentVolume", "pv-1", "pv1")
+	pv.SetNamespace("")
+	svc := newTestObject("v1", "Service", "app", "s1")
+	slice := newTestObject("discovery.k8s.io/v1", "EndpointSlice", "app-abcde", "es1", "s1")
+	slice.SetLabels(map[string]string{"kubernetes.io/service-name": "app"})
+
+	objs := newObjectDirectory([]unstructured.Unstructured{
+		pod, pvc, pv, svc, slice,
+		newTestObject("v1", "ConfigMap", "app-config", "cm1"),
+		newTestObject("v1", "Secret", "app-secret", "sec1"),
+		newTestObject("v1", "ServiceAccount", "app-sa", "sa1"),
+		newTestObject("v1", "Endpoints", "app", "ep1"),
+	})
+
+	tests := []struct {
+		name      string
+		relations []string
+		parent    string
+		want      map[string]string
+	}{
+		{name: "no relations", parent: "p1", want: map[string]string{}},
+		{name: "volumes", relations: []string{"volumes"}, parent: "p1", want: map[string]string{"data": "mounts"}},
+		{name: "bound volume", relations: []string{"volumes"}, parent: "pvc1", want: map[string]string{"pv-1": "binds"}},
+		{name: "config", relations: []string{"config"}, parent: "p1",
+			want: map[string]string{"app-config": "uses", "app-secret": "uses", "app-sa": "uses"}},
+		{name: "owned endpointslice is not duplicated", relations: []string{"services"}, parent: "s1",
+			want: map[string]string{"app-abcde": "", "app": "endpoints"}},
+		{name: "all", relations: []string{"all"}, parent: "p1",
+			want: map[string]string{"data": "mounts", "app-config": "uses", "app-secret": "uses", "app-sa": "uses"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			objs.resolveRelations(tt.relations)
+			got := make(map[string]string)
+			for _, c := range objs.children(types.UID(tt.parent)) {
+				got[c.GetName()] = c.relation
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("children of %s = %v, want %v", tt.parent, got, tt.want)
+			}
+			for k, v := range tt.want {
+				if rel, ok := got[k]; !ok || rel != v {
+					t.Fatalf("children of %s = %v, want %v", tt.parent, got, tt.want)
+				}
+			}
+		})
+	}
+}
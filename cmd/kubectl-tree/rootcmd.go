@@ -17,15 +17,21 @@ package main
 
 import (
 	"context"
+	stderrors "errors"
 	"flag"
 	"fmt"
 	"os"
+	"os/signal"
+	"slices"
+	"strings"
+	"time"
 
 	"github.com/fatih/color"
 	"github.com/pkg/errors"
 	"github.com/spf13/cobra"
 	"github.com/spf13/pflag"
-	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/cli-runtime/pkg/genericclioptions"
 	"k8s.io/cli-runtime/pkg/resource"
 	"k8s.io/client-go/discovery"
@@ -37,30 +43,62 @@ import (
 )
 
 const (
-	allNamespacesFlag  = "all-namespaces"
-	colorFlag          = "color"
-	conditionTypesFlag = "condition-types"
-	selectorFlag       = "selector"
-	apiGroupsFlag      = "api-groups"
-	resourcesFlag      = "resources"
+	allNamespacesFlag     = "all-namespaces"
+	colorFlag             = "color"
+	conditionTypesFlag    = "condition-types"
+	selectorFlag          = "selector"
+	apiGroupsFlag         = "api-groups"
+	resourcesFlag         = "resources"
+	outputFlag            = "output"
+	upFlag                = "up"
+	ownersFlag            = "owners" // alias of --up
+	watchFlag             = "watch"
+	relationsFlag         = "relations"
+	refreshDiscoveryFlag  = "refresh-discovery"
+	discoveryCacheTTLFlag = "discovery-cache-ttl"
+	fromFileFlag          = "from-file"
+	depthFlag             = "depth"
+	collapseFlag          = "collapse"
+	summaryFlag           = "summary"
+	failOnFlag            = "fail-on"
+	waitForFlag           = "wait-for"
+	timeoutFlag           = "timeout"
+	eventsFlag            = "events"
+	eventsSinceFlag       = "events-since"
+	columnsFlag           = "columns"
+	lintFlag              = "lint"
+	allFlag               = "all"
+	showKindsFlag         = "show-kinds"
+	onlyStatusFlag        = "only-status"
+	onlyNotReadyFlag      = "only-not-ready"
+	includeOwnersFlag     = "include-owners"
+	deletePreviewFlag     = "delete-preview"
+	cascadeFlag           = "cascade"
+	interactiveFlag       = "interactive"
+	saveSnapshotFlag      = "save-snapshot"
+	fromSnapshotFlag      = "from-snapshot"
+	contextsFlag          = "contexts"
+	allContextsFlag       = "all-contexts"
+	combinedFlag          = "combined"
 )
 
-var (
-	cf             *genericclioptions.ConfigFlags
-	conditionTypes []string
-)
+var cf *genericclioptions.ConfigFlags
 
 // This variable is populated by goreleaser
 var version string
 
 // rootCmd represents the base command when called without any subcommands
 var rootCmd = &cobra.Command{
-	Use:          "kubectl tree KIND NAME",
+	Use:          "kubectl tree (KIND [NAME...] | --all)",
 	SilenceUsage: true, // for when RunE returns an error
 	Short:        "Show sub-resources of the Kubernetes object",
 	Example: "  kubectl tree deployment my-app\n" +
-		"  kubectl tree kservice.v1.serving.knative.dev my-app", // TODO add more examples about disambiguation etc
-	Args:    cobra.RangeArgs(1, 2),
+		"  kubectl tree deployment my-app -f cluster-dump.yaml\n" +
+		"  kubectl tree kservice.v1.serving.knative.dev my-app\n" + // TODO add more examples about disambiguation etc
+		"  kubectl tree deployments my-app my-other-app\n" +
+		"  kubectl tree deployments -l app=my-app\n" +
+		"  kubectl tree --all -n my-namespace",
+	Args:    cobra.ArbitraryArgs, // validated in run, since there are no arguments with --all
 	RunE:    run,
 	Version: versionString(),
 }
@@ -81,19 +119,11 @@ func run(command *cobra.Command, args []string) error {
 		allNs = false
 	}
 
-	colorArg, err := command.Flags().GetString(colorFlag)
-	if err != nil {
+	if err := setColor(command); err != nil {
 		return err
 	}
-	if colorArg == "always" {
-		color.NoColor = false
-	} else if colorArg == "never" {
-		color.NoColor = true
-	} else if colorArg != "auto" {
-		return errors.Errorf("invalid value for --%s", colorFlag)
-	}
 
-	conditionTypes, err = command.Flags().GetStringSlice(conditionTypesFlag)
+	conditionTypes, err := command.Flags().GetStringSlice(conditionTypesFlag)
 	if err != nil {
 		return err
 	}
@@ -113,47 +143,489 @@ func run(command *cobra.Command, args []string) error {
 		return err
 	}
 
-	restConfig, err := cf.ToRESTConfig()
+	includeOwners, err := command.Flags().GetBool(includeOwnersFlag)
 	if err != nil {
 		return err
 	}
-	restConfig.WarningHandler = rest.NoWarnings{}
-	restConfig.QPS = 1000
-	restConfig.Burst = 1000
-	dyn, err := dynamic.NewForConfig(restConfig)
+	// without filters, the owners are queried anyway
+	includeOwners = includeOwners && (len(apiGroups) > 0 || len(resources) > 0)
+
+	outputFormat, err := command.Flags().GetString(outputFlag)
+	if err != nil {
+		return err
+	}
+	var columns []treeColumn
+	customColumns := strings.HasPrefix(outputFormat, outputCustomColumns+"=")
+	if customColumns {
+		columns, err = parseColumns(strings.TrimPrefix(outputFormat, outputCustomColumns+"="))
+		if err != nil {
+			return errors.Errorf("invalid value for --%s: %v", outputFlag, err)
+		}
+		outputFormat = ""
+	}
+	if !validOutputFormat(outputFormat) {
+		return errors.Errorf("invalid value for --%s: %q (supported: %s, %s=...)", outputFlag, outputFormat, strings.Join(outputFormats, ", "), outputCustomColumns)
+	}
+
+	columnsSpec, err := command.Flags().GetString(columnsFlag)
+	if err != nil {
+		return err
+	}
+	if columnsSpec != "" {
+		if outputFormat != "" && outputFormat != outputWide {
+			return errors.Errorf("--%s can only be used with the table output", columnsFlag)
+		}
+		extra, err := parseColumns(columnsSpec)
+		if err != nil {
+			return errors.Errorf("invalid value for --%s: %v", columnsFlag, err)
+		}
+		columns = append(columns, extra...)
+	}
+
+	depth, err := command.Flags().GetInt(depthFlag)
+	if err != nil {
+		return err
+	}
+	if depth < 0 {
+		return errors.Errorf("invalid value for --%s: must be 0 or greater", depthFlag)
+	}
+
+	collapse, err := command.Flags().GetStringSlice(collapseFlag)
+	if err != nil {
+		return err
+	}
+
+	summary, err := command.Flags().GetBool(summaryFlag)
+	if err != nil {
+		return err
+	}
+
+	failOn, err := command.Flags().GetStringSlice(failOnFlag)
+	if err != nil {
+		return err
+	}
+	if err := validateFailOn(failOn); err != nil {
+		return errors.Errorf("invalid value for --%s: %v", failOnFlag, err)
+	}
+
+	showKinds, err := command.Flags().GetStringSlice(showKindsFlag)
+	if err != nil {
+		return err
+	}
+	onlyStatus, err := command.Flags().GetStringSlice(onlyStatusFlag)
+	if err != nil {
+		return err
+	}
+	if err := validateStatusFilters(onlyStatus); err != nil {
+		return errors.Errorf("invalid value for --%s: %v", onlyStatusFlag, err)
+	}
+	onlyNotReady, err := command.Flags().GetBool(onlyNotReadyFlag)
+	if err != nil {
+		return err
+	}
+
+	opts := treeOptions{
+		conditionTypes: conditionTypes,
+		depth:          depth,
+		collapse:       newKindSelector(collapse),
+		summary:        summary,
+		columns:        columns,
+		customColumns:  customColumns,
+		wide:           outputFormat == outputWide,
+		filter:         treeFilter{kinds: newKindSelector(showKinds), statuses: onlyStatus, notReady: onlyNotReady},
+	}
+	if opts.wide {
+		outputFormat = ""
+	}
+
+	up, err := command.Flags().GetBool(upFlag)
+	if err != nil {
+		return err
+	}
+
+	watchMode, err := command.Flags().GetBool(watchFlag)
+	if err != nil {
+		return err
+	}
+	if watchMode && up {
+		return errors.Errorf("--%s cannot be used with --%s", watchFlag, upFlag)
+	}
+	if watchMode && len(failOn) > 0 {
+		return errors.Errorf("--%s cannot be used with --%s", failOnFlag, watchFlag)
+	}
+
+	forest, err := command.Flags().GetBool(allFlag)
+	if err != nil {
+		return err
+	}
+	fromSnapshot, err := command.Flags().GetString(fromSnapshotFlag)
+	if err != nil {
+		return err
+	}
+	switch {
+	case forest && len(args) > 0:
+		return errors.Errorf("--%s cannot be used with a KIND or NAME", allFlag)
+	case !forest && len(args) == 0 && fromSnapshot == "":
+		return errors.Errorf("requires a KIND argument, or --%s", allFlag)
+	case forest && (up || watchMode):
+		return errors.Errorf("--%s cannot be used with --%s or --%s", allFlag, upFlag, watchFlag)
+	}
+
+	lint, err := command.Flags().GetBool(lintFlag)
+	if err != nil {
+		return err
+	}
+	if lint && watchMode {
+		return errors.Errorf("--%s cannot be used with --%s", lintFlag, watchFlag)
+	}
+
+	waitFor, err := command.Flags().GetString(waitForFlag)
+	if err != nil {
+		return err
+	}
+	if err := validateWaitFor(waitFor); err != nil {
+		return errors.Errorf("invalid value for --%s: %v", waitForFlag, err)
+	}
+	if waitFor != "" && (up || watchMode || forest) {
+		return errors.Errorf("--%s cannot be used with --%s, --%s or --%s", waitForFlag, upFlag, watchFlag, allFlag)
+	}
+
+	timeout, err := command.Flags().GetDuration(timeoutFlag)
+	if err != nil {
+		return err
+	}
+
+	showEvents, err := command.Flags().GetBool(eventsFlag)
+	if err != nil {
+		return err
+	}
+	if showEvents && watchMode {
+		return errors.Errorf("--%s cannot be used with --%s", eventsFlag, watchFlag)
+	}
+
+	eventsSince, err := command.Flags().GetDuration(eventsSinceFlag)
+	if err != nil {
+		return err
+	}
+
+	deletePreview, err := command.Flags().GetBool(deletePreviewFlag)
+	if err != nil {
+		return err
+	}
+	cascade, err := command.Flags().GetString(cascadeFlag)
+	if err != nil {
+		return err
+	}
+	propagation, err := propagationPolicy(cascade)
+	if err != nil {
+		return errors.Errorf("invalid value for --%s: %v", cascadeFlag, err)
+	}
+	if deletePreview {
+		if up || watchMode || waitFor != "" {
+			return errors.Errorf("--%s cannot be used with --%s, --%s or --%s", deletePreviewFlag, upFlag, watchFlag, waitForFlag)
+		}
+		if outputFormat != "" {
+			return errors.Errorf("--%s can only be used with the table output formats", deletePreviewFlag)
+		}
+		opts.deletePreview = propagation
+	}
+
+	interactive, err := command.Flags().GetBool(interactiveFlag)
+	if err != nil {
+		return err
+	}
+	if interactive {
+		if err := checkInteractiveFlags(command.Flags()); err != nil {
+			return err
+		}
+	}
+
+	relations, err := command.Flags().GetStringSlice(relationsFlag)
+	if err != nil {
+		return err
+	}
+	if err := validateRelations(relations); err != nil {
+		return errors.Errorf("invalid value for --%s: %v", relationsFlag, err)
+	}
+	if len(relations) > 0 && up {
+		return errors.Errorf("--%s cannot be used with --%s", relationsFlag, upFlag)
+	}
+
+	refresh, err := command.Flags().GetBool(refreshDiscoveryFlag)
+	if err != nil {
+		return err
+	}
+
+	cacheTTL, err := command.Flags().GetDuration(discoveryCacheTTLFlag)
+	if err != nil {
+		return err
+	}
+
+	files, err := command.Flags().GetStringSlice(fromFileFlag)
 	if err != nil {
-		return fmt.Errorf("failed to construct dynamic client: %w", err)
+		return err
 	}
-	dc, err := discovery.NewDiscoveryClientForConfig(restConfig)
+	saveSnapshot, err := command.Flags().GetString(saveSnapshotFlag)
+	if err != nil {
+		return err
+	}
+	if saveSnapshot != "" && (up || watchMode || waitFor != "" || interactive || len(files) > 0 || fromSnapshot != "") {
+		return errors.Errorf("--%s cannot be used with --%s, --%s, --%s, --%s, --%s or --%s", saveSnapshotFlag, upFlag, watchFlag, waitForFlag, interactiveFlag, fromFileFlag, fromSnapshotFlag)
+	}
+
+	contexts, err := command.Flags().GetStringSlice(contextsFlag)
+	if err != nil {
+		return err
+	}
+	allContexts, err := command.Flags().GetBool(allContextsFlag)
+	if err != nil {
+		return err
+	}
+	combined, err := command.Flags().GetBool(combinedFlag)
+	if err != nil {
+		return err
+	}
+	if allContexts {
+		if len(contexts) > 0 {
+			return errors.Errorf("--%s cannot be used with --%s", allContextsFlag, contextsFlag)
+		}
+		if contexts, err = kubeconfigContexts(cf); err != nil {
+			return err
+		}
+		if len(contexts) == 0 {
+			return errors.New("no contexts found in the kubeconfig")
+		}
+	}
+	if combined && len(contexts) == 0 {
+		return errors.Errorf("--%s requires --%s or --%s", combinedFlag, contextsFlag, allContextsFlag)
+	}
+	q := treeQuery{
+		args:          args,
+		forest:        forest,
+		allNs:         allNs,
+		labelSelector: labelSelector,
+		apiGroups:     apiGroups,
+		resources:     resources,
+		includeOwners: includeOwners,
+		refresh:       refresh,
+		cacheTTL:      cacheTTL,
+	}
+	if len(contexts) > 0 {
+		if up || watchMode || waitFor != "" || interactive || showEvents {
+			return errors.Errorf("--%s and --%s cannot be used with --%s, --%s, --%s, --%s or --%s", contextsFlag, allContextsFlag, upFlag, watchFlag, waitForFlag, interactiveFlag, eventsFlag)
+		}
+		if len(files) > 0 || fromSnapshot != "" || saveSnapshot != "" {
+			return errors.Errorf("--%s and --%s cannot be used with --%s, --%s or --%s", contextsFlag, allContextsFlag, fromFileFlag, fromSnapshotFlag, saveSnapshotFlag)
+		}
+		if outputFormat != "" {
+			return errors.Errorf("--%s and --%s can only be used with the table output formats", contextsFlag, allContextsFlag)
+		}
+		return runContexts(contexts, q, relations, opts, combined, treeChecks{failOn, lint})
+	}
+	if len(files) > 0 || fromSnapshot != "" {
+		if up || watchMode || waitFor != "" {
+			return errors.Errorf("--%s and --%s cannot be used with --%s, --%s or --%s", fromFileFlag, fromSnapshotFlag, upFlag, watchFlag, waitForFlag)
+		}
+		namespace := ptr.Deref(cf.Namespace, "")
+		if allNs {
+			namespace = ""
+		}
+		var apiObjects, savedRoots []unstructured.Unstructured
+		var single bool
+		if fromSnapshot != "" {
+			if len(files) > 0 {
+				return errors.Errorf("--%s cannot be used with --%s", fromSnapshotFlag, fromFileFlag)
+			}
+			s, err := readSnapshot(fromSnapshot)
+			if err != nil {
+				return err
+			}
+			klog.V(2).Infof("loaded snapshot of context %q created at %v", s.Context, s.Created)
+			apiObjects = s.objects()
+			if !forest {
+				savedRoots, single = s.roots(), s.Single
+			}
+		} else {
+			loaded, err := loadObjects(files)
+			if err != nil {
+				return err
+			}
+			apiObjects = offlineObjects(loaded)
+		}
+		return runOffline(apiObjects, savedRoots, single, args, namespace, labelSelector, outputFormat, relations, opts, treeChecks{failOn, lint}, showEvents || interactive, eventsSince, interactive)
+	}
+
+	src, err := q.connect(cf)
 	if err != nil {
-		return fmt.Errorf("failed to construct discovery client: %w", err)
+		return err
+	}
+	if opts, err = opts.resolveKinds(src.all); err != nil {
+		return err
 	}
+	roots, single := src.roots, src.single
 
-	apis, err := findAPIs(dc, apiGroups, resources)
+	// events are queried right before the trees are printed, so that they are as recent as the objects
+	var events []unstructured.Unstructured
+	loadEvents := func() error {
+		if !showEvents {
+			return nil
+		}
+		evs, err := getEvents(src.dyn, src.namespace)
+		if err != nil {
+			return err
+		}
+		events = evs
+		opts.events = indexEvents(evs, eventsSince)
+		return nil
+	}
+
+	if up {
+		mapper, err := cf.ToRESTMapper()
+		if err != nil {
+			return fmt.Errorf("failed to construct rest mapper: %w", err)
+		}
+		klog.V(2).Infof("querying owners of the objects")
+		objs := newOwnerDirectory(src.dyn, mapper, roots...)
+		if err := loadEvents(); err != nil {
+			return err
+		}
+		if err := printTrees(outputFormat, objs, roots, single, opts, "This object has no owners through ownerReferences."); err != nil {
+			return err
+		}
+		klog.V(2).Infof("done printing owners tree view")
+		return treeChecks{failOn, lint}.run(outputFormat, objs, roots, conditionTypes)
+	}
+
+	if interactive {
+		return runInteractive(func() (objectDirectory, []unstructured.Unstructured, eventIndex, error) {
+			listed, err := src.list()
+			if err != nil {
+				return objectDirectory{}, nil, nil, err
+			}
+			evs, err := getEvents(src.dyn, src.namespace)
+			if err != nil {
+				return objectDirectory{}, nil, nil, err
+			}
+			objs := newObjectDirectory(listed.objects)
+			objs.resolveRelations(relations)
+			return objs, src.treeRoots(objs), indexEvents(evs, eventsSince), nil
+		}, opts)
+	}
+
+	if watchMode {
+		ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt)
+		defer cancel()
+		listed, err := src.list()
+		if err != nil {
+			return err
+		}
+		return watchTree(ctx, src.dyn, src.apis, src.namespace, labelSelector, listed, roots, relations, watchRenderer(outputFormat, single, opts))
+	}
+
+	if waitFor != "" {
+		ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt)
+		defer cancel()
+		if timeout > 0 {
+			ctx, cancel = context.WithTimeout(ctx, timeout)
+			defer cancel()
+		}
+		listed, err := src.list()
+		if err != nil {
+			return err
+		}
+		objs, roots, waitErr := waitForTree(ctx, src.dyn, src.apis, src.namespace, labelSelector, listed, roots, relations, waitFor, conditionTypes)
+		if objs.items == nil {
+			// the objects could not be queried
+			return waitErr
+		}
+		if err := loadEvents(); err != nil {
+			return err
+		}
+		if err := printTrees(outputFormat, objs, roots, single, opts, noOwnedResourcesMessage); err != nil {
+			return err
+		}
+		if waitErr != nil {
+			return waitErr
+		}
+		return treeChecks{failOn, lint}.run(outputFormat, objs, roots, conditionTypes)
+	}
+
+	listed, err := src.list()
 	if err != nil {
 		return err
 	}
-	klog.V(3).Info("completed querying APIs list")
+	objs := newObjectDirectory(listed.objects)
+	objs.resolveRelations(relations)
+	if roots = src.treeRoots(objs); forest && len(roots) == 0 {
+		fmt.Println(noTopLevelOwnersMessage)
+		return nil
+	}
+	if err := loadEvents(); err != nil {
+		return err
+	}
+	if saveSnapshot != "" {
+		if err := writeSnapshot(saveSnapshot, newSnapshot(contextName(cf), roots, single, append(listed.objects, events...))); err != nil {
+			return err
+		}
+		klog.V(2).Infof("saved snapshot to %s", saveSnapshot)
+	}
+	if err := printTrees(outputFormat, objs, roots, single, opts, noOwnedResourcesMessage); err != nil {
+		return err
+	}
+	klog.V(2).Infof("done printing tree view")
+	return treeChecks{failOn, lint}.run(outputFormat, objs, roots, conditionTypes)
+}
 
+// normalizeFlagAliases maps the aliases of flags to the flags.
+func normalizeFlagAliases(_ *pflag.FlagSet, name string) pflag.NormalizedName {
+	if name == ownersFlag {
+		name = upFlag
+	}
+	return pflag.NormalizedName(name)
+}
+
+// interactiveConflicts are the flags that change how the trees are printed or queried in ways the terminal UI doesn't
+// support.
+var interactiveConflicts = []string{upFlag, watchFlag, waitForFlag, deletePreviewFlag, outputFlag, columnsFlag}
+
+// checkInteractiveFlags returns an error if any of the flags that conflict with --interactive is set. The raw flags are
+// checked, since e.g. -o wide and -o custom-columns=... are turned into options of the table view.
+func checkInteractiveFlags(flags *pflag.FlagSet) error {
+	for _, name := range interactiveConflicts {
+		if flags.Changed(name) {
+			return errors.Errorf("--%s cannot be used with --%s", interactiveFlag, name)
+		}
+	}
+	return nil
+}
+
+// resolveRoots finds the root objects specified with the KIND [NAME...] or KIND/NAME... arguments. It returns whether a
+// single object was specified by name.
+func resolveRoots(f *genericclioptions.ConfigFlags, args []string, allNs bool, labelSelector string) ([]unstructured.Unstructured, bool, error) {
 	// Use resource.Builder to resolve resource kind and name (kubectl-compatible)
-	clientCfg := cf.ToRawKubeConfigLoader()
+	clientCfg := f.ToRawKubeConfigLoader()
 	kubeconfigNamespace, _, err := clientCfg.Namespace()
 	if err != nil {
-		return fmt.Errorf("failed to determine namespace from kubeconfig: %w", err)
+		return nil, false, fmt.Errorf("failed to determine namespace from kubeconfig: %w", err)
 	}
 
-	rb := resource.NewBuilder(cf)
+	rb := resource.NewBuilder(f)
 
-	namespace := ptr.Deref(cf.Namespace, "")
+	namespace := ptr.Deref(f.Namespace, "")
 	if namespace != "" {
 		rb = rb.NamespaceParam(namespace)
 	} else if kubeconfigNamespace != "" {
 		rb = rb.NamespaceParam(kubeconfigNamespace)
 	}
-	result := rb.
+	rb = rb.
 		Unstructured().
 		AllNamespaces(allNs).
-		ResourceTypeOrNameArgs(true, args...).
+		ResourceTypeOrNameArgs(true, args...)
+	if labelSelector != "" && len(args) == 1 && !strings.Contains(args[0], "/") {
+		// only the KIND is specified, so the selector also picks the root objects
+		rb = rb.LabelSelectorParam(labelSelector)
+	}
+	result := rb.
 		Latest().
 		Flatten().
 		ContinueOnError().
@@ -161,61 +633,177 @@ func run(command *cobra.Command, args []string) error {
 
 	infos, err := result.Infos()
 	if err != nil {
-		return fmt.Errorf("failed to resolve resource: %w", err)
+		return nil, false, fmt.Errorf("failed to resolve resource: %w", err)
 	}
 	if len(infos) == 0 {
-		return fmt.Errorf("no resources found")
+		return nil, false, fmt.Errorf("no resources found")
 	}
-	if len(infos) > 1 {
-		return fmt.Errorf("multiple resources found, specify a single resource")
+	var roots []unstructured.Unstructured
+	for _, info := range infos {
+		obj, ok := info.Object.(*unstructured.Unstructured)
+		if !ok {
+			return nil, false, fmt.Errorf("unexpected object type %T for %s/%s", info.Object, info.Mapping.Resource.Resource, info.Name)
+		}
+		klog.V(3).Infof("resolved resource: gvr=%v namespace=%v name=%v", info.Mapping.Resource, info.Namespace, info.Name)
+		klog.V(5).Infof("target parent object: %#v", obj)
+		roots = append(roots, *obj)
 	}
-	info := infos[0]
-	gvr := info.Mapping.Resource
-	name := info.Name
-	klog.V(3).Infof("resolved resource: gvr=%v name=%v", gvr, name)
+	// print a list of trees in structured output, unless a single object is specified by name
+	single := len(roots) == 1 && result.TargetsSingleItems()
+	return roots, single, nil
+}
 
-	// Convert GVR to apiResource for compatibility with existing code
-	// Check if resource is namespaced by comparing scope name
-	isNamespaced := info.Mapping.Scope.Name() == "namespace"
-	api := apiResource{
-		r: metav1.APIResource{
-			Name:       gvr.Resource,
-			Namespaced: isNamespaced,
-		},
-		gv: gvr.GroupVersion(),
+// runOffline builds the trees from objects loaded from files or a snapshot instead of querying the cluster. The trees
+// are of the objects specified with args, or of the saved roots of a snapshot, or of the top-level owners otherwise. If
+// events are shown, they are taken from the Events among the loaded objects.
+func runOffline(apiObjects, savedRoots []unstructured.Unstructured, single bool, args []string, namespace, labelSelector, outputFormat string, relations []string, opts treeOptions, checks treeChecks, showEvents bool, eventsSince time.Duration, interactive bool) error {
+	if showEvents {
+		opts.events = indexEvents(apiObjects, eventsSince)
 	}
+	if labelSelector != "" {
+		sel, err := labels.Parse(labelSelector)
+		if err != nil {
+			return fmt.Errorf("invalid label selector: %w", err)
+		}
+		apiObjects = slices.DeleteFunc(apiObjects, func(obj unstructured.Unstructured) bool {
+			return !sel.Matches(labels.Set(obj.GetLabels()))
+		})
+	}
+	klog.V(2).Infof("loaded total %d api objects", len(apiObjects))
+
+	objs := newObjectDirectory(apiObjects)
+	objs.resolveRelations(relations)
+	var roots []unstructured.Unstructured
+	switch {
+	case len(args) > 0:
+		// the saved roots are stored separately in snapshots, since their kinds may not have been queried
+		var err error
+		roots, single, err = findRoots(offlineObjects(append(slices.Clip(apiObjects), savedRoots...)), args, namespace)
+		if err != nil {
+			return fmt.Errorf("failed to resolve resource: %w", err)
+		}
+	case len(savedRoots) > 0:
+		roots = latestRoots(objs, savedRoots)
+	default:
+		// --all
+		if roots = objs.topLevelOwners(namespace); len(roots) == 0 {
+			fmt.Println(noTopLevelOwnersMessage)
+			return nil
+		}
+	}
+	if interactive {
+		return runInteractive(func() (objectDirectory, []unstructured.Unstructured, eventIndex, error) {
+			return objs, roots, opts.events, nil
+		}, opts)
+	}
+	if err := printTrees(outputFormat, objs, roots, single, opts, noOwnedResourcesMessage); err != nil {
+		return err
+	}
+	return checks.run(outputFormat, objs, roots, opts.conditionTypes)
+}
 
-	ns := getNamespace()
-	klog.V(2).Infof("namespace=%s allNamespaces=%v", ns, allNs)
+// treeChecks are the checks of the printed trees that make the command fail.
+type treeChecks struct {
+	failOn []string
+	lint   bool
+}
 
-	var ri dynamic.ResourceInterface
-	if api.r.Namespaced {
-		ri = dyn.Resource(gvr).Namespace(ns)
-	} else {
-		ri = dyn.Resource(gvr)
+// run runs the checks on the trees. The problems found by --lint are printed after the table, or to stderr with other
+// output formats.
+func (c treeChecks) run(outputFormat string, objs objectDirectory, roots []unstructured.Unstructured, conditionTypes []string) error {
+	var lintErr error
+	if c.lint {
+		out := color.Output
+		if outputFormat != "" {
+			out = color.Error
+		} else {
+			fmt.Fprintln(out)
+		}
+		lintErr = checkLint(out, objs, roots)
 	}
-	obj, err := ri.Get(context.TODO(), name, metav1.GetOptions{})
+	return stderrors.Join(lintErr, checkFailOn(objs, roots, conditionTypes, c.failOn))
+}
+
+// setColor enables or disables color output as specified with --color.
+func setColor(command *cobra.Command) error {
+	colorArg, err := command.Flags().GetString(colorFlag)
 	if err != nil {
-		return fmt.Errorf("failed to get %s/%s: %w", gvr.Resource, name, err)
+		return err
 	}
+	if colorArg == "always" {
+		color.NoColor = false
+	} else if colorArg == "never" {
+		color.NoColor = true
+	} else if colorArg != "auto" {
+		return errors.Errorf("invalid value for --%s", colorFlag)
+	}
+	return nil
+}
 
-	klog.V(5).Infof("target parent object: %#v", obj)
-
-	klog.V(2).Infof("querying all api objects")
-	apiObjects, err := getAllResources(dyn, apis.resources(), allNs, labelSelector)
+// newClients returns the dynamic client and the discovery client (with API discovery results cached for cacheTTL,
+// unless refresh is set) for the cluster of the context of the config flags.
+func newClients(f *genericclioptions.ConfigFlags, refresh bool, cacheTTL time.Duration) (dynamic.Interface, discovery.CachedDiscoveryInterface, error) {
+	restConfig, err := f.ToRESTConfig()
+	if err != nil {
+		return nil, nil, err
+	}
+	restConfig.WarningHandler = rest.NoWarnings{}
+	restConfig.QPS = 1000
+	restConfig.Burst = 1000
+	dyn, err := dynamic.NewForConfig(restConfig)
 	if err != nil {
-		return fmt.Errorf("error while querying api objects: %w", err)
+		return nil, nil, fmt.Errorf("failed to construct dynamic client: %w", err)
 	}
-	klog.V(2).Infof("found total %d api objects", len(apiObjects))
+	dc, err := newCachedDiscoveryClient(f, restConfig, cacheTTL)
+	if err != nil {
+		return nil, nil, err
+	}
+	if refresh {
+		klog.V(2).Infof("invalidating discovery cache")
+		dc.Invalidate()
+		// also used by resource.Builder to resolve the resource kind
+		if kdc, err := f.ToDiscoveryClient(); err == nil {
+			kdc.Invalidate()
+		}
+	}
+	return dyn, dc, nil
+}
 
-	objs := newObjectDirectory(apiObjects)
-	if len(objs.ownership[obj.GetUID()]) == 0 {
-		fmt.Println("No resources are owned by this object through ownerReferences.")
+const (
+	noOwnedResourcesMessage = "No resources are owned by this object through ownerReferences."
+	noTopLevelOwnersMessage = "No objects that own other objects through ownerReferences were found."
+)
+
+// printTrees prints the hierarchy of each root in the specified output format. If there is a single root with nothing
+// under it, emptyMessage is printed instead of a table. With other output formats, the summary is printed to stderr
+// to keep the output parseable. The display filters of opts are applied to all output formats.
+func printTrees(outputFormat string, objs objectDirectory, roots []unstructured.Unstructured, single bool, opts treeOptions, emptyMessage string) error {
+	if outputFormat == "" && len(roots) == 1 && len(objs.children(roots[0].GetUID())) == 0 {
+		fmt.Println(emptyMessage)
 		return nil
 	}
-	treeView(color.Output, objs, *obj, conditionTypes)
-	klog.V(2).Infof("done printing tree view")
-	return nil
+	objs, opts = opts.prepare(objs, roots)
+
+	var err error
+	switch outputFormat {
+	case "":
+		treeView(color.Output, objs, roots, opts)
+		if opts.deletion != nil {
+			fmt.Fprintln(color.Output)
+			opts.deletion.print(color.Output, objs)
+		}
+		return nil
+	case outputDot:
+		err = dotView(os.Stdout, objs, roots, opts)
+	case outputMermaid:
+		err = mermaidView(os.Stdout, objs, roots, opts)
+	default:
+		err = structuredView(os.Stdout, outputFormat, objs, roots, single, opts)
+	}
+	if err == nil && opts.summary {
+		summarize(objs, roots, opts.conditionTypes).print(color.Error)
+	}
+	return err
 }
 
 func init() {
@@ -231,14 +819,46 @@ func init() {
 
 	cf = genericclioptions.NewConfigFlags(true)
 
-	rootCmd.Flags().BoolP(allNamespacesFlag, "A", false, "query all objects in all API groups, both namespaced and non-namespaced")
-	rootCmd.Flags().StringP(colorFlag, "c", "auto", "Enable or disable color output. This can be 'always', 'never', or 'auto' (default = use color only if using tty). The flag is overridden by the NO_COLOR env variable if set.")
+	rootCmd.PersistentFlags().BoolP(allNamespacesFlag, "A", false, "query all objects in all API groups, both namespaced and non-namespaced")
+	rootCmd.PersistentFlags().StringP(colorFlag, "c", "auto", "Enable or disable color output. This can be 'always', 'never', or 'auto' (default = use color only if using tty). The flag is overridden by the NO_COLOR env variable if set.")
 	rootCmd.Flags().StringSlice(conditionTypesFlag, []string{"Ready"}, "Comma-separated list of condition types to check (default: Ready). Example: Ready,Processed,Scheduled")
-	rootCmd.Flags().StringP(selectorFlag, "l", "", "Selector (label query) to filter on, supports '=', '==', and '!='. (e.g. -l key1=value1,key2=value2)")
-	rootCmd.Flags().StringSlice(apiGroupsFlag, nil, "Comma-separated list of API groups to include in the query, when not set all APIs are included, globs are supported (e.g. --api-groups=core,cluster.x-k8s.io,*.cert-manager.io)")
-	rootCmd.Flags().StringSlice(resourcesFlag, nil, "Comma-separated list of resource types to include in the query, when not set all resources are included, globs are supported (e.g. --resources=deployments,rs,pods)")
-
-	cf.AddFlags(rootCmd.Flags())
+	rootCmd.PersistentFlags().StringP(selectorFlag, "l", "", "Selector (label query) to filter on, supports '=', '==', and '!='. (e.g. -l key1=value1,key2=value2)")
+	rootCmd.PersistentFlags().StringSlice(apiGroupsFlag, nil, "Comma-separated list of API groups to include in the query, when not set all APIs are included, globs are supported (e.g. --api-groups=core,cluster.x-k8s.io,*.cert-manager.io)")
+	rootCmd.PersistentFlags().StringSlice(resourcesFlag, nil, "Comma-separated list of resource types to include in the query, when not set all resources are included, globs are supported (e.g. --resources=deployments,rs,pods)")
+	rootCmd.Flags().StringP(outputFlag, "o", "", "Output format. One of: json, yaml (nested objects), dot, mermaid (ownership graph), wide (table with more details), custom-columns=HEADER:JSONPATH,... (table with the specified columns). When not set, the tree is printed as a table.")
+	rootCmd.Flags().String(columnsFlag, "", "Comma-separated list of columns in HEADER:JSONPATH form to add to the table (e.g. --columns=REPLICAS:.spec.replicas,NODE:.spec.nodeName)")
+	rootCmd.Flags().BoolP(watchFlag, "w", false, "After printing the tree, watch the objects and print the tree again whenever it changes")
+	rootCmd.Flags().StringSlice(relationsFlag, nil, fmt.Sprintf("Comma-separated list of logical relationships (not expressed through ownerReferences) to show in the tree, one or more of: %s, or %s", strings.Join(relationshipResolverNames(), ", "), allRelations))
+	rootCmd.Flags().Bool(includeOwnersFlag, false, fmt.Sprintf("Also query the resource types of the owners of the objects found with --%s and --%s (and of their owners, and so on), so that the tree is not broken by the resource types that were left out", resourcesFlag, apiGroupsFlag))
+	rootCmd.PersistentFlags().Bool(refreshDiscoveryFlag, false, "Ignore the cached API discovery results and query the server for available APIs")
+	rootCmd.PersistentFlags().Duration(discoveryCacheTTLFlag, defaultDiscoveryCacheTTL, "How long the API discovery results are cached under --cache-dir (set to 0 to disable caching)")
+	rootCmd.PersistentFlags().StringSliceP(fromFileFlag, "f", nil, "Build the tree from objects in the specified files, directories or tar archives (e.g. a 'kubectl get -o yaml' dump or a must-gather archive) instead of querying the cluster, use '-' for stdin")
+	rootCmd.Flags().Int(depthFlag, 0, "Maximum depth of the objects shown under the root object, 0 means no limit")
+	rootCmd.Flags().StringSlice(collapseFlag, nil, "Comma-separated list of resource types whose objects are summarized in a single row instead of being expanded (e.g. --collapse=replicasets,pods)")
+	rootCmd.Flags().Bool(summaryFlag, false, "Print the number of objects in the tree by their status and readiness after the tree")
+	rootCmd.Flags().StringSlice(failOnFlag, nil, fmt.Sprintf("Exit with an error if any object in the tree matches one of the comma-separated conditions: %s", strings.Join(failOnConditions, ", ")))
+	rootCmd.Flags().String(waitForFlag, "", fmt.Sprintf("Wait until all objects in the tree reach the condition, one of: %s, and then print the tree", strings.Join(waitForConditions, ", ")))
+	rootCmd.Flags().Duration(timeoutFlag, defaultWaitTimeout, fmt.Sprintf("How long to wait with --%s before giving up, 0 means wait forever", waitForFlag))
+	rootCmd.Flags().Bool(eventsFlag, false, "Show the most recent warning events of each object under it")
+	rootCmd.Flags().Duration(eventsSinceFlag, defaultEventsSince, fmt.Sprintf("Only show events with --%s that occurred within this duration, 0 means no limit", eventsFlag))
+	rootCmd.Flags().Bool(lintFlag, false, "Report problems with the ownerReferences of the objects in the tree (cycles, missing owners, owners in other namespaces, multiple controllers) and exit with an error if there are any")
+	rootCmd.Flags().Bool(allFlag, false, "Show the tree of every object that has no owners but owns other objects, instead of specifying the root objects")
+	rootCmd.Flags().StringSlice(showKindsFlag, nil, "Comma-separated list of resource types to show in the tree (e.g. --show-kinds=pods), along with the objects on the path to them from the root")
+	rootCmd.Flags().StringSlice(onlyStatusFlag, nil, fmt.Sprintf("Comma-separated list of statuses of the objects to show in the tree, along with the objects on the path to them from the root, one or more of: %s", strings.Join(statusFilters(), ", ")))
+	rootCmd.Flags().Bool(onlyNotReadyFlag, false, "Only show the objects whose READY is False or Unknown in the tree, along with the objects on the path to them from the root")
+	rootCmd.Flags().Bool(deletePreviewFlag, false, "Show what the garbage collector would delete or orphan if the root objects were deleted, without deleting anything")
+	rootCmd.Flags().String(cascadeFlag, "background", fmt.Sprintf("The propagation policy of the deletion with --%s, one of: %s (same as kubectl delete --cascade)", deletePreviewFlag, strings.Join(cascadeModes, ", ")))
+	rootCmd.Flags().BoolP(interactiveFlag, "i", false, "Browse the tree in a terminal UI, with collapsible objects and the details, conditions and events of the selected object, refreshed periodically")
+	rootCmd.Flags().String(saveSnapshotFlag, "", fmt.Sprintf("Save the queried objects and the root objects to the specified file (gzip compressed JSON), to render the trees again later with --%s or compare them with 'kubectl tree diff'. The data of Secrets and managedFields are not saved", fromSnapshotFlag))
+	rootCmd.Flags().String(fromSnapshotFlag, "", fmt.Sprintf("Build the trees from a file saved with --%s instead of querying the cluster, the KIND argument is optional", saveSnapshotFlag))
+	rootCmd.Flags().StringSlice(contextsFlag, nil, "Comma-separated list of kubeconfig contexts to show the trees of, queried in parallel, instead of the current context")
+	rootCmd.Flags().Bool(allContextsFlag, false, fmt.Sprintf("Show the trees in all contexts of the kubeconfig, like --%s", contextsFlag))
+	rootCmd.Flags().Bool(combinedFlag, false, fmt.Sprintf("With --%s or --%s, print the trees of all contexts in a single table with a CLUSTER column, instead of one table per context", contextsFlag, allContextsFlag))
+	rootCmd.Flags().Bool(upFlag, false, fmt.Sprintf("Show the owners of the object instead of the objects it owns, by following ownerReferences upwards to the root(s) (alias: --%s)", ownersFlag))
+	rootCmd.Flags().SetNormalizeFunc(normalizeFlagAliases)
+
+	// kubeconfig flags (e.g. --context, --namespace), shared with the subcommands like the persistent flags above
+	cf.AddFlags(rootCmd.PersistentFlags())
 	if err := flag.Set("logtostderr", "true"); err != nil {
 		fmt.Fprintf(os.Stderr, "failed to set logtostderr flag: %v\n", err)
 		os.Exit(1)
@@ -0,0 +1,56 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/spf13/pflag"
+)
+
+func TestCheckInteractiveFlags(t *testing.T) {
+	tests := []struct {
+		args    []string
+		wantErr string
+	}{
+		{args: []string{"-i"}},
+		{args: []string{"-i", "--depth=2"}},
+		{args: []string{"-i", "-o", "wide"}, wantErr: "--interactive cannot be used with --output"},
+		{args: []string{"-i", "-o", "custom-columns=NAME:.metadata.name"}, wantErr: "--interactive cannot be used with --output"},
+		{args: []string{"-i", "--columns", "NODE:.spec.nodeName"}, wantErr: "--interactive cannot be used with --columns"},
+		{args: []string{"-i", "--up"}, wantErr: "--interactive cannot be used with --up"},
+		{args: []string{"-i", "--wait-for=ready"}, wantErr: "--interactive cannot be used with --wait-for"},
+	}
+	for _, tt := range tests {
+		flags := pflag.NewFlagSet("test", pflag.ContinueOnError)
+		flags.BoolP(interactiveFlag, "i", false, "")
+		flags.Int(depthFlag, 0, "")
+		flags.StringP(outputFlag, "o", "", "")
+		flags.String(columnsFlag, "", "")
+		flags.Bool(upFlag, false, "")
+		flags.Bool(watchFlag, false, "")
+		flags.String(waitForFlag, "", "")
+		flags.Bool(deletePreviewFlag, false, "")
+		if err := flags.Parse(tt.args); err != nil {
+			t.Fatal(err)
+		}
+		err := checkInteractiveFlags(flags)
+		var got string
+		if err != nil {
+			got = err.Error()
+		}
+		if got != tt.wantErr {
+			t.Errorf("%v: got error %q, want %q", tt.args, got, tt.wantErr)
+		}
+	}
+}
+
+func TestNormalizeFlagAliases(t *testing.T) {
+	flags := pflag.NewFlagSet("test", pflag.ContinueOnError)
+	flags.Bool(upFlag, false, "")
+	flags.SetNormalizeFunc(normalizeFlagAliases)
+	if err := flags.Parse([]string{"--owners"}); err != nil {
+		t.Fatal(err)
+	}
+	if up, err := flags.GetBool(upFlag); err != nil || !up {
+		t.Errorf("--owners did not set --up: %v, %v", up, err)
+	}
+}
@@ -0,0 +1,167 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	utiljson "k8s.io/apimachinery/pkg/util/json"
+)
+
+// snapshotVersion is the version of the snapshot format written by this version of the tool.
+const snapshotVersion = 1
+
+// snapshot is the queried state of the trees saved with --save-snapshot: the objects (and events, if they were
+// queried) and the root objects, which can be rendered again with --from-snapshot.
+type snapshot struct {
+	Version int       `json:"version"`
+	Created time.Time `json:"created"`
+	Context string    `json:"context,omitempty"`
+	// Single is whether a single root object was specified by name, which prints a single tree in structured output.
+	Single bool `json:"single,omitempty"`
+
+	// Roots are the root objects of the trees. They are stored separately, since their kinds may not be queried.
+	Roots   []map[string]interface{} `json:"roots"`
+	Objects []map[string]interface{} `json:"objects"`
+}
+
+// newSnapshot returns the snapshot of the objects, which are redacted with redactForSnapshot.
+func newSnapshot(context string, roots []unstructured.Unstructured, single bool, objs []unstructured.Unstructured) snapshot {
+	s := snapshot{Version: snapshotVersion, Created: time.Now().UTC().Truncate(time.Second), Context: context, Single: single}
+	for _, obj := range roots {
+		s.Roots = append(s.Roots, redactForSnapshot(obj).Object)
+	}
+	for _, obj := range objs {
+		s.Objects = append(s.Objects, redactForSnapshot(obj).Object)
+	}
+	return s
+}
+
+const (
+	// redactedValue replaces the values of the keys of Secrets in snapshots.
+	redactedValue = "REDACTED"
+
+	lastAppliedConfigAnnotation = "kubectl.kubernetes.io/last-applied-configuration"
+)
+
+// redactForSnapshot returns a copy of obj without its managedFields, which are not needed to render the trees, and
+// without the payload of Secrets (including the last applied configuration), since snapshots are meant to be shared.
+// The keys of Secrets are kept.
+func redactForSnapshot(obj unstructured.Unstructured) *unstructured.Unstructured {
+	out := obj.DeepCopy()
+	out.SetManagedFields(nil)
+	if out.GroupVersionKind().GroupKind() != (schema.GroupKind{Kind: "Secret"}) {
+		return out
+	}
+	for _, field := range []string{"data", "stringData"} {
+		values, ok := out.Object[field].(map[string]interface{})
+		if !ok {
+			continue
+		}
+		for k := range values {
+			values[k] = redactedValue
+		}
+	}
+	if annotations := out.GetAnnotations(); annotations[lastAppliedConfigAnnotation] != "" {
+		annotations[lastAppliedConfigAnnotation] = redactedValue
+		out.SetAnnotations(annotations)
+	}
+	return out
+}
+
+func (s snapshot) roots() []unstructured.Unstructured { return snapshotObjects(s.Roots) }
+
+func (s snapshot) objects() []unstructured.Unstructured { return snapshotObjects(s.Objects) }
+
+func snapshotObjects(objs []map[string]interface{}) []unstructured.Unstructured {
+	out := make([]unstructured.Unstructured, 0, len(objs))
+	for _, obj := range objs {
+		out = append(out, unstructured.Unstructured{Object: obj})
+	}
+	return out
+}
+
+// writeSnapshot writes the snapshot to path as gzipped JSON.
+func writeSnapshot(path string, s snapshot) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("failed to create snapshot: %w", err)
+	}
+	defer f.Close()
+	zw := gzip.NewWriter(f)
+	if err := json.NewEncoder(zw).Encode(s); err != nil {
+		return fmt.Errorf("failed to write snapshot: %w", err)
+	}
+	if err := zw.Close(); err != nil {
+		return fmt.Errorf("failed to write snapshot: %w", err)
+	}
+	return f.Close()
+}
+
+// readSnapshot reads a snapshot written with writeSnapshot.
+func readSnapshot(path string) (snapshot, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return snapshot{}, err
+	}
+	defer f.Close()
+	zr, err := gzip.NewReader(f)
+	if err != nil {
+		return snapshot{}, fmt.Errorf("%s is not a snapshot: %w", path, err)
+	}
+	b, err := io.ReadAll(zr)
+	if err != nil {
+		return snapshot{}, fmt.Errorf("failed to read snapshot: %w", err)
+	}
+	// the objects are decoded separately with utiljson, which keeps integers as int64 like the objects returned by the
+	// API server
+	var raw struct {
+		snapshot
+		Roots   []json.RawMessage `json:"roots"`
+		Objects []json.RawMessage `json:"objects"`
+	}
+	if err := json.Unmarshal(b, &raw); err != nil {
+		return snapshot{}, fmt.Errorf("failed to decode snapshot: %w", err)
+	}
+	s := raw.snapshot
+	if s.Version != snapshotVersion {
+		return snapshot{}, fmt.Errorf("unsupported snapshot version %d (supported: %d)", s.Version, snapshotVersion)
+	}
+	if s.Roots, err = decodeSnapshotObjects(raw.Roots); err != nil {
+		return snapshot{}, err
+	}
+	if s.Objects, err = decodeSnapshotObjects(raw.Objects); err != nil {
+		return snapshot{}, err
+	}
+	return s, nil
+}
+
+func decodeSnapshotObjects(raw []json.RawMessage) ([]map[string]interface{}, error) {
+	out := make([]map[string]interface{}, 0, len(raw))
+	for _, r := range raw {
+		var obj map[string]interface{}
+		if err := utiljson.Unmarshal(r, &obj); err != nil {
+			return nil, fmt.Errorf("failed to decode object in snapshot: %w", err)
+		}
+		out = append(out, obj)
+	}
+	return out, nil
+}
+
+// isSnapshot reports whether the file at path is gzip compressed, like snapshots.
+func isSnapshot(path string) bool {
+	f, err := os.Open(path)
+	if err != nil {
+		return false
+	}
+	defer f.Close()
+	magic, err := bufio.NewReader(f).Peek(2)
+	return err == nil && bytes.Equal(magic, []byte{0x1f, 0x8b})
+}
@@ -0,0 +1,87 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+func TestSnapshot(t *testing.T) {
+	deploy := newTestObject("apps/v1", "Deployment", "app", "d1")
+	if err := unstructured.SetNestedField(deploy.Object, int64(3), "spec", "replicas"); err != nil {
+		t.Fatal(err)
+	}
+	rs := newTestObject("apps/v1", "ReplicaSet", "app-1", "rs1", "d1")
+	pod := newTestObject("v1", "Pod", "app-1-a", "p1", "rs1")
+
+	path := filepath.Join(t.TempDir(), "app.snapshot")
+	if err := writeSnapshot(path, newSnapshot("prod", []unstructured.Unstructured{deploy}, true, []unstructured.Unstructured{deploy, rs, pod})); err != nil {
+		t.Fatal(err)
+	}
+	if !isSnapshot(path) {
+		t.Fatal("snapshot is not detected")
+	}
+	s, err := readSnapshot(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s.Context != "prod" || !s.Single || len(s.roots()) != 1 || len(s.objects()) != 3 {
+		t.Fatalf("unexpected snapshot: %+v", s)
+	}
+	// integers are decoded as int64, like the objects returned by the API server
+	if v, _, _ := unstructured.NestedInt64(s.roots()[0].Object, "spec", "replicas"); v != 3 {
+		t.Errorf("got replicas %v, want 3", s.roots()[0].Object["spec"])
+	}
+	objs := newObjectDirectory(s.objects())
+	if got := len(objs.ownership[s.roots()[0].GetUID()]); got != 1 {
+		t.Errorf("got %d children of the root, want 1", got)
+	}
+
+	other := filepath.Join(t.TempDir(), "tree.json")
+	if err := os.WriteFile(other, []byte("[]"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if isSnapshot(other) {
+		t.Error("JSON file is detected as a snapshot")
+	}
+	if _, err := readSnapshot(other); err == nil || !strings.Contains(err.Error(), "is not a snapshot") {
+		t.Errorf("unexpected error for a JSON file: %v", err)
+	}
+}
+
+func TestSnapshotRedaction(t *testing.T) {
+	deploy := newTestObject("apps/v1", "Deployment", "app", "d1")
+	deploy.SetManagedFields([]metav1.ManagedFieldsEntry{{Manager: "kubectl", Operation: metav1.ManagedFieldsOperationApply}})
+	secret := newTestObject("v1", "Secret", "token", "s1", "d1")
+	secret.Object["data"] = map[string]interface{}{"token": "c2VjcmV0"}
+	secret.Object["stringData"] = map[string]interface{}{"password": "secret"}
+	secret.SetAnnotations(map[string]string{lastAppliedConfigAnnotation: `{"data":{"token":"c2VjcmV0"}}`, "team": "payments"})
+	// objects that happen to be named Secret in other API groups are kept
+	custom := newTestObject("example.com/v1", "Secret", "custom", "c1", "d1")
+	custom.Object["data"] = map[string]interface{}{"key": "value"}
+
+	s := newSnapshot("prod", []unstructured.Unstructured{deploy}, true, []unstructured.Unstructured{deploy, secret, custom})
+	if b, err := json.Marshal(s); err != nil {
+		t.Fatal(err)
+	} else if strings.Contains(string(b), "c2VjcmV0") || strings.Contains(string(b), `"secret"`) || strings.Contains(string(b), "managedFields") {
+		t.Errorf("snapshot contains secret data or managedFields: %s", b)
+	}
+	objs := s.objects()
+	if got, _, _ := unstructured.NestedStringMap(objs[1].Object, "data"); got["token"] != redactedValue {
+		t.Errorf("got Secret data %v, want the keys with redacted values", got)
+	}
+	if got := objs[1].GetAnnotations(); got["team"] != "payments" || got[lastAppliedConfigAnnotation] != redactedValue {
+		t.Errorf("unexpected Secret annotations: %v", got)
+	}
+	if got, _, _ := unstructured.NestedStringMap(objs[2].Object, "data"); got["key"] != "value" {
+		t.Errorf("got data %v of a custom Secret kind, want it unchanged", got)
+	}
+	if secret.Object["data"].(map[string]interface{})["token"] != "c2VjcmV0" || len(deploy.GetManagedFields()) != 1 {
+		t.Error("the queried objects are modified")
+	}
+}
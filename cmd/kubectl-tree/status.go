@@ -11,28 +11,31 @@ import (
 type ReadyStatus string // True False Unknown or ""
 type Reason string
 
+// extractStatus returns the first of the condition types found in the status of obj as its ready status and reason,
+// and the kstatus of obj, which doesn't depend on the condition types (e.g. a Job with a Failed=True condition is
+// Failed even without a Ready condition).
 func extractStatus(obj unstructured.Unstructured, conditionTypes []string) (ReadyStatus, Reason, status.Status) {
 	jsonVal, _ := json.Marshal(obj.Object["status"])
 	klog.V(6).Infof("status for object=%s/%s: %s", obj.GetKind(), obj.GetName(), string(jsonVal))
-	result, err := status.Compute(&obj)
-	if err != nil {
-		return "", "", ""
+	var kstatus status.Status
+	if result, err := status.Compute(&obj); err == nil {
+		kstatus = result.Status
 	}
 	statusF, ok := obj.Object["status"]
 	if !ok {
-		return "", "", ""
+		return "", "", kstatus
 	}
 	statusV, ok := statusF.(map[string]interface{})
 	if !ok {
-		return "", "", ""
+		return "", "", kstatus
 	}
 	conditionsF, ok := statusV["conditions"]
 	if !ok {
-		return "", "", ""
+		return "", "", kstatus
 	}
 	conditionsV, ok := conditionsF.([]interface{})
 	if !ok {
-		return "", "", ""
+		return "", "", kstatus
 	}
 
 	// Check each requested condition type in order
@@ -49,9 +52,9 @@ func extractStatus(obj unstructured.Unstructured, conditionTypes []string) (Read
 			if condType == targetCondType {
 				condStatus, _ := condM["status"].(string)
 				condReason, _ := condM["reason"].(string)
-				return ReadyStatus(condStatus), Reason(condReason), status.Status(result.Status.String())
+				return ReadyStatus(condStatus), Reason(condReason), kstatus
 			}
 		}
 	}
-	return "", "", ""
+	return "", "", kstatus
 }
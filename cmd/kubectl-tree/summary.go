@@ -0,0 +1,162 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/cli-utils/pkg/kstatus/status"
+)
+
+// summaryStatuses is the order in which the kstatus results are listed in the summary. Objects without a status
+// (e.g. ConfigMaps) are counted as "".
+var summaryStatuses = []status.Status{status.CurrentStatus, status.InProgressStatus, status.FailedStatus,
+	status.TerminatingStatus, status.UnknownStatus, status.NotFoundStatus, ""}
+
+// summaryReady is the order in which the READY values are listed in the summary.
+var summaryReady = []ReadyStatus{"True", "False", "Unknown", ""}
+
+// treeSummary is the number of objects in the trees by their kstatus result and READY value.
+type treeSummary struct {
+	total    int
+	statuses map[status.Status]int
+	ready    map[ReadyStatus]int
+}
+
+// treeObjects returns the distinct objects in the trees of the roots, including the roots, regardless of the depth
+// or collapsing options of the view.
+func treeObjects(objs objectDirectory, roots []unstructured.Unstructured) []unstructured.Unstructured {
+	var out []unstructured.Unstructured
+	visited := make(map[types.UID]bool)
+	var visit func(obj unstructured.Unstructured)
+	visit = func(obj unstructured.Unstructured) {
+		if visited[obj.GetUID()] {
+			return
+		}
+		visited[obj.GetUID()] = true
+		out = append(out, obj)
+		for _, child := range objs.children(obj.GetUID()) {
+			visit(child.Unstructured)
+		}
+	}
+	for _, root := range roots {
+		visit(root)
+	}
+	return out
+}
+
+func summarize(objs objectDirectory, roots []unstructured.Unstructured, conditionTypes []string) treeSummary {
+	s := treeSummary{
+		statuses: make(map[status.Status]int),
+		ready:    make(map[ReadyStatus]int),
+	}
+	for _, obj := range treeObjects(objs, roots) {
+		ready, _, kstatus := objs.statusOf(obj, conditionTypes)
+		s.total++
+		s.statuses[kstatus]++
+		s.ready[ready]++
+	}
+	return s
+}
+
+// print writes the summary, e.g.:
+//
+//	12 objects
+//	STATUS: 10 Current, 1 InProgress, 1 Failed
+//	READY:  9 True, 1 False, 2 -
+func (s treeSummary) print(out io.Writer) {
+	noun := "objects"
+	if s.total == 1 {
+		noun = "object"
+	}
+	fmt.Fprintf(out, "%d %s\n", s.total, noun)
+
+	var statuses []string
+	for _, st := range summaryStatuses {
+		if n := s.statuses[st]; n > 0 {
+			statuses = append(statuses, statusColor(st).Sprintf("%d %s", n, orDash(string(st))))
+		}
+	}
+	fmt.Fprintf(out, "STATUS: %s\n", strings.Join(statuses, ", "))
+
+	var ready []string
+	for _, r := range summaryReady {
+		if n := s.ready[r]; n > 0 {
+			ready = append(ready, readyColor(r).Sprintf("%d %s", n, orDash(string(r))))
+		}
+	}
+	fmt.Fprintf(out, "READY:  %s\n", strings.Join(ready, ", "))
+}
+
+func orDash(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
+
+// Values of --fail-on.
+const (
+	failOnFailed      = "failed"
+	failOnInProgress  = "inprogress"
+	failOnTerminating = "terminating"
+	failOnUnknown     = "unknown"
+	failOnNotReady    = "notready"
+)
+
+var failOnConditions = []string{failOnFailed, failOnInProgress, failOnTerminating, failOnUnknown, failOnNotReady}
+
+// validateFailOn returns an error if any of the specified --fail-on conditions is not supported.
+func validateFailOn(conditions []string) error {
+	for _, c := range conditions {
+		if !slices.Contains(failOnConditions, c) {
+			return fmt.Errorf("unknown condition %q (supported: %s)", c, strings.Join(failOnConditions, ", "))
+		}
+	}
+	return nil
+}
+
+// matchesFailOn returns the first of the --fail-on conditions that matches the status of an object, or "".
+func matchesFailOn(conditions []string, ready ReadyStatus, kstatus status.Status) string {
+	for _, c := range conditions {
+		var match bool
+		switch c {
+		case failOnFailed:
+			match = kstatus == status.FailedStatus
+		case failOnInProgress:
+			match = kstatus == status.InProgressStatus
+		case failOnTerminating:
+			match = kstatus == status.TerminatingStatus
+		case failOnUnknown:
+			match = kstatus == status.UnknownStatus
+		case failOnNotReady:
+			match = ready == "False" || ready == "Unknown"
+		}
+		if match {
+			return c
+		}
+	}
+	return ""
+}
+
+// checkFailOn returns an error listing the objects in the trees that match any of the --fail-on conditions.
+func checkFailOn(objs objectDirectory, roots []unstructured.Unstructured, conditionTypes, conditions []string) error {
+	if len(conditions) == 0 {
+		return nil
+	}
+	var matched []string
+	for _, obj := range treeObjects(objs, roots) {
+		ready, _, kstatus := objs.statusOf(obj, conditionTypes)
+		if c := matchesFailOn(conditions, ready, kstatus); c != "" {
+			matched = append(matched, fmt.Sprintf("%s/%s (%s)", obj.GetKind(), obj.GetName(), c))
+		}
+	}
+	if len(matched) == 0 {
+		return nil
+	}
+	return fmt.Errorf("%d object(s) matched --%s=%s: %s", len(matched), failOnFlag, strings.Join(conditions, ","),
+		strings.Join(matched, ", "))
+}
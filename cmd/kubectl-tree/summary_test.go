@@ -0,0 +1,86 @@
+package main
+
+import (
+	"testing"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/cli-utils/pkg/kstatus/status"
+)
+
+func TestSummarize(t *testing.T) {
+	deploy := newTestObject("apps/v1", "Deployment", "app", "d1")
+	ready := newTestObject("v1", "Pod", "app-a", "p1", "d1")
+	ready.Object["status"] = map[string]interface{}{
+		"phase":      "Running",
+		"conditions": []interface{}{map[string]interface{}{"type": "Ready", "status": "True"}},
+	}
+	notReady := newTestObject("v1", "Pod", "app-b", "p2", "d1")
+	notReady.Object["status"] = map[string]interface{}{
+		"phase":      "Running",
+		"conditions": []interface{}{map[string]interface{}{"type": "Ready", "status": "False", "reason": "ContainersNotReady"}},
+	}
+	objs := newObjectDirectory([]unstructured.Unstructured{deploy, ready, notReady})
+	roots := []unstructured.Unstructured{deploy}
+
+	s := summarize(objs, roots, []string{"Ready"})
+	if s.total != 3 || s.ready["True"] != 1 || s.ready["False"] != 1 || s.ready[""] != 1 {
+		t.Fatalf("unexpected summary: %+v", s)
+	}
+	if s.statuses[status.CurrentStatus] != 1 || s.statuses[status.InProgressStatus] != 2 {
+		t.Fatalf("unexpected statuses in summary: %+v", s.statuses)
+	}
+
+	tests := []struct {
+		failOn  []string
+		wantErr bool
+	}{
+		{failOn: nil, wantErr: false},
+		{failOn: []string{failOnFailed}, wantErr: false},
+		{failOn: []string{failOnNotReady}, wantErr: true},
+		{failOn: []string{failOnFailed, failOnInProgress}, wantErr: true},
+	}
+	for _, tt := range tests {
+		if err := checkFailOn(objs, roots, []string{"Ready"}, tt.failOn); (err != nil) != tt.wantErr {
+			t.Errorf("checkFailOn(%v) = %v, want error: %v", tt.failOn, err, tt.wantErr)
+		}
+	}
+}
+
+// newFailedDeployment returns a Deployment that exceeded its progress deadline, which has no Ready condition.
+func newFailedDeployment(name string, uid types.UID) unstructured.Unstructured {
+	obj := newTestObject("apps/v1", "Deployment", name, uid)
+	obj.Object["status"] = map[string]interface{}{
+		"conditions": []interface{}{map[string]interface{}{"type": "Progressing", "status": "False", "reason": "ProgressDeadlineExceeded"}},
+	}
+	return obj
+}
+
+// newFailedJob returns a Job with a Failed condition, which has no Ready condition.
+func newFailedJob(name string, uid types.UID, owners ...types.UID) unstructured.Unstructured {
+	obj := newTestObject("batch/v1", "Job", name, uid, owners...)
+	obj.Object["status"] = map[string]interface{}{
+		"conditions": []interface{}{map[string]interface{}{"type": "Failed", "status": "True", "reason": "BackoffLimitExceeded"}},
+	}
+	return obj
+}
+
+func TestSummarizeWithoutReadyCondition(t *testing.T) {
+	deploy := newFailedDeployment("app", "d1")
+	job := newFailedJob("migrate", "j1")
+	objs := newObjectDirectory([]unstructured.Unstructured{deploy, job})
+	roots := []unstructured.Unstructured{deploy, job}
+
+	s := summarize(objs, roots, []string{"Ready"})
+	if s.statuses[status.FailedStatus] != 2 || s.ready[""] != 2 {
+		t.Fatalf("unexpected summary: %+v", s)
+	}
+	for _, failOn := range [][]string{{failOnFailed}, {failOnFailed, failOnInProgress}} {
+		if err := checkFailOn(objs, roots, []string{"Ready"}, failOn); err == nil {
+			t.Errorf("checkFailOn(%v) = nil, want an error for the failed Deployment and Job", failOn)
+		}
+	}
+	if err := checkFailOn(objs, roots, []string{"Ready"}, []string{failOnNotReady}); err != nil {
+		t.Errorf("checkFailOn(%v) = %v, want nil for objects without a Ready condition", failOnNotReady, err)
+	}
+}
@@ -8,7 +8,9 @@ import (
 
 	"github.com/fatih/color"
 	"github.com/gosuri/uitable"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/apimachinery/pkg/util/duration"
 	"sigs.k8s.io/cli-utils/pkg/kstatus/status"
 )
@@ -25,44 +27,112 @@ var (
 	red    = color.New(color.FgRed)
 	yellow = color.New(color.FgYellow)
 	green  = color.New(color.FgGreen)
+	cyan   = color.New(color.FgCyan)
 )
 
-// treeView prints object hierarchy to out stream.
-func treeView(out io.Writer, objs objectDirectory, obj unstructured.Unstructured, conditionTypes []string) {
+// treeOptions controls how the objects are shown in the tree.
+type treeOptions struct {
+	// conditionTypes are the condition types that determine the READY and REASON of an object, in order of preference.
+	conditionTypes []string
+
+	// depth is the maximum depth of the objects shown under the roots, or 0 for no limit.
+	depth int
+
+	// collapse are the resource types (e.g. replicasets or rs) whose objects are summarized in a single row
+	// instead of being shown with their descendants.
+	collapse kindSelector
+
+	// summary prints the number of objects in the trees by their status after the table.
+	summary bool
+
+	// events are the recent warning events shown under the objects, or nil if events are not shown.
+	events eventIndex
+
+	// columns are shown after the default columns, or instead of them (except NAMESPACE and NAME) if customColumns
+	// is set.
+	columns       []treeColumn
+	customColumns bool
+
+	// wide adds the flags of the ownerReference to the parent and kind-specific details of the objects to the table.
+	wide bool
+
+	// filter selects the objects shown under the roots, see treeFilter.
+	filter treeFilter
+
+	// deletePreview is the propagation policy of the deletion of the roots to preview, or empty to not show the
+	// preview. printTrees sets deletion to the predicted outcome, which is shown in the ACTION and NOTE columns.
+	deletePreview metav1.DeletionPropagation
+	deletion      *deletePlan
+}
+
+// prepare predicts the outcome of the deletion of the roots if it's previewed, and returns the objects shown in the
+// trees with the options to print them.
+func (opts treeOptions) prepare(objs objectDirectory, roots []unstructured.Unstructured) (objectDirectory, treeOptions) {
+	if opts.deletePreview != "" {
+		plan := planDeletion(objs, roots, opts.deletePreview)
+		opts.deletion = &plan
+	}
+	return opts.filter.apply(objs, roots, opts.conditionTypes), opts
+}
+
+// treeView prints object hierarchy of each root to out stream, in a single table.
+func treeView(out io.Writer, objs objectDirectory, roots []unstructured.Unstructured, opts treeOptions) {
+	fmt.Fprintln(out, treeTable(objs, roots, opts))
+	if opts.summary {
+		fmt.Fprintln(out)
+		summarize(objs, roots, opts.conditionTypes).print(out)
+	}
+}
+
+// treeTable returns the table of the trees printed by treeView, with the header in the first row.
+func treeTable(objs objectDirectory, roots []unstructured.Unstructured, opts treeOptions) *uitable.Table {
 	tbl := uitable.New()
 	tbl.Separator = "  "
-	tbl.AddRow("NAMESPACE", "NAME", "READY", "REASON", "STATUS", "AGE")
-	treeViewInner("", tbl, objs, obj, conditionTypes)
-	fmt.Fprintln(out, tbl)
+	header := []interface{}{"NAMESPACE", "NAME"}
+	if !opts.customColumns {
+		header = append(header, "READY", "REASON", "STATUS", "AGE")
+	}
+	if opts.wide {
+		header = append(header, "OWNERREF", "DETAILS")
+	}
+	for _, c := range opts.columns {
+		header = append(header, c.header)
+	}
+	if opts.deletion != nil {
+		header = append(header, "ACTION", "NOTE")
+	}
+	tbl.AddRow(header...)
+	visits := make(map[types.UID]visitState)
+	for i, obj := range roots {
+		if i > 0 {
+			tbl.AddRow()
+		}
+		treeViewInner("", tbl, objs, treeChild{Unstructured: obj}, 0, opts, visits)
+	}
+	return tbl
 }
 
-func treeViewInner(prefix string, tbl *uitable.Table, objs objectDirectory, obj unstructured.Unstructured, conditionTypes []string) {
-	ready, reason, kstatus := extractStatus(obj, conditionTypes)
+// visitState is the state of an object while printing the tree.
+type visitState int
 
-	var readyColor *color.Color
-	switch ready {
-	case "True":
-		readyColor = green
-	case "False", "Unknown":
-		readyColor = red
-	default:
-		readyColor = gray
-	}
+const (
+	notVisited visitState = iota
+	// visiting objects are the ancestors of the object being printed, so reaching them again means there's a cycle
+	visiting
+	visited
+)
+
+// treeViewInner adds the rows of obj and its descendants to tbl. Objects that are already printed (e.g. objects with
+// multiple owners) are added as a reference to the first occurrence, without their descendants, which also stops
+// ownerReference cycles.
+func treeViewInner(prefix string, tbl *uitable.Table, objs objectDirectory, obj treeChild, depth int, opts treeOptions, visits map[types.UID]visitState) {
+	visits[obj.GetUID()] = visiting
+	defer func() { visits[obj.GetUID()] = visited }()
+	ready, reason, kstatus := objs.statusOf(obj.Unstructured, opts.conditionTypes)
+	readyColor, statusColor := readyColor(ready), statusColor(kstatus)
 	if ready == "" {
 		ready = "-"
 	}
-
-	var statusColor *color.Color
-	switch kstatus {
-	case status.CurrentStatus:
-		statusColor = green
-	case status.InProgressStatus:
-		statusColor = yellow
-	case status.FailedStatus, status.TerminatingStatus:
-		statusColor = red
-	default:
-		statusColor = gray
-	}
 	if kstatus == "" {
 		kstatus = "-"
 	}
@@ -73,15 +143,52 @@ func treeViewInner(prefix string, tbl *uitable.Table, objs objectDirectory, obj
 		age = "<unknown>"
 	}
 
-	tbl.AddRow(obj.GetNamespace(), fmt.Sprintf("%s%s/%s",
+	var rel string
+	if obj.relation != "" {
+		rel = cyan.Sprintf("[%s] ", obj.relation)
+	}
+
+	row := []interface{}{obj.GetNamespace(), fmt.Sprintf("%s%s%s/%s",
 		gray.Sprint(printPrefix(prefix)),
+		rel,
 		obj.GetKind(),
-		color.New(color.Bold).Sprint(obj.GetName())),
-		readyColor.Sprint(ready),
-		readyColor.Sprint(reason),
-		statusColor.Sprint(kstatus),
-		age)
-	chs := objs.ownedBy(obj.GetUID())
+		color.New(color.Bold).Sprint(obj.GetName()))}
+	if !opts.customColumns {
+		row = append(row,
+			readyColor.Sprint(ready),
+			readyColor.Sprint(reason),
+			statusColor.Sprint(kstatus),
+			age)
+	}
+	if opts.wide {
+		row = append(row, ownerRefFlags(obj.ownerRef), wideDetails(obj.Unstructured))
+	}
+	for _, c := range opts.columns {
+		row = append(row, c.value(obj.Unstructured))
+	}
+	if opts.deletion != nil {
+		row = append(row, opts.deletion.actionColumns(obj.GetUID())...)
+	}
+	tbl.AddRow(row...)
+	chs := collapseChildren(objs.children(obj.GetUID()), opts.collapse)
+	truncated := opts.depth > 0 && depth >= opts.depth
+	if evs := opts.events[obj.GetUID()]; len(evs) > 0 {
+		p := strings.TrimSuffix(printPrefix(prefix+firstElemPrefix), firstElemPrefix)
+		if len(chs) > 0 {
+			p += pipe
+		} else {
+			p += indent
+		}
+		for _, ev := range evs {
+			tbl.AddRow("", gray.Sprint(p)+yellow.Sprint("⚠ "+ev.String()))
+		}
+	}
+	if truncated {
+		if n := countDescendants(objs, obj.GetUID()); n > 0 {
+			tbl.AddRow("", gray.Sprintf("%s… %d more", printPrefix(prefix+lastElemPrefix), n))
+		}
+		return
+	}
 	for i, child := range chs {
 		var p string
 		switch i {
@@ -90,8 +197,125 @@ func treeViewInner(prefix string, tbl *uitable.Table, objs objectDirectory, obj
 		default:
 			p = prefix + firstElemPrefix
 		}
-		treeViewInner(p, tbl, objs, child, conditionTypes)
+		if len(child.collapsed) > 0 {
+			tbl.AddRow(obj.GetNamespace(), gray.Sprint(printPrefix(p))+collapsedSummary(child.collapsed))
+			continue
+		}
+		switch visits[child.GetUID()] {
+		case visiting:
+			tbl.AddRow(child.GetNamespace(), gray.Sprint(printPrefix(p))+red.Sprintf("↻ cycle back to %s/%s", child.GetKind(), child.GetName()))
+		case visited:
+			tbl.AddRow(child.GetNamespace(), gray.Sprint(printPrefix(p))+gray.Sprint(seeAbove(child.treeChild)))
+		default:
+			treeViewInner(p, tbl, objs, child.treeChild, depth+1, opts, visits)
+		}
+	}
+}
+
+func readyColor(ready ReadyStatus) *color.Color {
+	switch ready {
+	case "True":
+		return green
+	case "False", "Unknown":
+		return red
+	default:
+		return gray
+	}
+}
+
+func statusColor(kstatus status.Status) *color.Color {
+	switch kstatus {
+	case status.CurrentStatus:
+		return green
+	case status.InProgressStatus:
+		return yellow
+	case status.FailedStatus, status.TerminatingStatus, status.NotFoundStatus:
+		return red
+	default:
+		return gray
+	}
+}
+
+// seeAbove describes a reference to an object that is already printed, with the owners that explain why it appears
+// more than once, e.g. "↪ see Pod/foo above (owners: ReplicaSet/a, ReplicaSet/b)".
+func seeAbove(obj treeChild) string {
+	var rel string
+	if obj.relation != "" {
+		rel = fmt.Sprintf("[%s] ", obj.relation)
+	}
+	s := fmt.Sprintf("↪ %ssee %s/%s above", rel, obj.GetKind(), obj.GetName())
+	if owners := ownerNames(obj.Unstructured); len(owners) > 1 {
+		s += fmt.Sprintf(" (owners: %s)", strings.Join(owners, ", "))
+	}
+	return s
+}
+
+// ownerNames returns the owners of obj in KIND/NAME form, from its ownerReferences.
+func ownerNames(obj unstructured.Unstructured) []string {
+	var owners []string
+	for _, ref := range obj.GetOwnerReferences() {
+		owners = append(owners, ref.Kind+"/"+ref.Name)
+	}
+	return owners
+}
+
+// treeItem is a row under an object in the tree: either a child, or a summary of the collapsed children of a kind.
+type treeItem struct {
+	treeChild
+	collapsed []treeChild
+}
+
+// collapseChildren groups the owned children whose kind matches one of the resource types in the collapse list.
+// Each group takes the place of its first member.
+func collapseChildren(chs []treeChild, collapse kindSelector) []treeItem {
+	var out []treeItem
+	groups := make(map[string]int)
+	for _, ch := range chs {
+		if ch.relation != "" || !collapse.matches(ch.Unstructured) {
+			out = append(out, treeItem{treeChild: ch})
+			continue
+		}
+		gk := ch.GroupVersionKind().GroupKind().String()
+		if i, ok := groups[gk]; ok {
+			out[i].collapsed = append(out[i].collapsed, ch)
+			continue
+		}
+		groups[gk] = len(out)
+		out = append(out, treeItem{treeChild: ch, collapsed: []treeChild{ch}})
+	}
+	return out
+}
+
+// collapsedSummary describes collapsed objects of the same kind, e.g. "ReplicaSet (12 more, 11 scaled to 0)".
+func collapsedSummary(objs []treeChild) string {
+	var scaledDown int
+	for _, o := range objs {
+		if replicas, ok, _ := unstructured.NestedInt64(o.Object, "spec", "replicas"); ok && replicas == 0 {
+			scaledDown++
+		}
+	}
+	s := fmt.Sprintf("%s (%d more", objs[0].GetKind(), len(objs))
+	if scaledDown > 0 {
+		s += fmt.Sprintf(", %d scaled to 0", scaledDown)
+	}
+	return s + ")"
+}
+
+// countDescendants returns the number of distinct objects under specified id.
+func countDescendants(objs objectDirectory, id types.UID) int {
+	visited := map[types.UID]bool{id: true}
+	queue := []types.UID{id}
+	for len(queue) > 0 {
+		cur := queue[0]
+		queue = queue[1:]
+		for _, ch := range objs.children(cur) {
+			if !visited[ch.GetUID()] {
+				visited[ch.GetUID()] = true
+				queue = append(queue, ch.GetUID())
+			}
+		}
 	}
+	return len(visited) - 1
 }
 
 func printPrefix(p string) string {
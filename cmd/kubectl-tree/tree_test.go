@@ -0,0 +1,107 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/fatih/color"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+func TestTreeViewSharedChildren(t *testing.T) {
+	noColor := color.NoColor
+	color.NoColor = true
+	defer func() { color.NoColor = noColor }()
+
+	deploy := newTestObject("apps/v1", "Deployment", "app", "d1")
+	shared := newTestObject("v1", "Pod", "shared", "p1")
+	shared.SetOwnerReferences([]metav1.OwnerReference{
+		{Kind: "ReplicaSet", Name: "app-1", UID: "rs1"},
+		{Kind: "ReplicaSet", Name: "app-2", UID: "rs2"},
+	})
+	objs := newObjectDirectory([]unstructured.Unstructured{
+		deploy,
+		newTestObject("apps/v1", "ReplicaSet", "app-1", "rs1", "d1"),
+		newTestObject("apps/v1", "ReplicaSet", "app-2", "rs2", "d1"),
+		shared,
+		newTestObject("v1", "Secret", "shared-child", "s1", "p1"),
+	})
+
+	var buf bytes.Buffer
+	treeView(&buf, objs, []unstructured.Unstructured{deploy}, treeOptions{conditionTypes: []string{"Ready"}})
+	out := buf.String()
+	if n := strings.Count(out, "─Pod/shared "); n != 1 {
+		t.Fatalf("expected the shared pod to be printed once, got %d times:\n%s", n, out)
+	}
+	if n := strings.Count(out, "Secret/shared-child"); n != 1 {
+		t.Fatalf("expected the subtree of the shared pod to be printed once, got %d times:\n%s", n, out)
+	}
+	if !strings.Contains(out, "↪ see Pod/shared above (owners: ReplicaSet/app-1, ReplicaSet/app-2)") {
+		t.Fatalf("expected a reference to the shared pod:\n%s", out)
+	}
+}
+
+func TestTreeViewCycle(t *testing.T) {
+	noColor := color.NoColor
+	color.NoColor = true
+	defer func() { color.NoColor = noColor }()
+
+	a := newTestObject("v1", "ConfigMap", "a", "a", "b")
+	b := newTestObject("v1", "ConfigMap", "b", "b", "a")
+	objs := newObjectDirectory([]unstructured.Unstructured{a, b})
+
+	var buf bytes.Buffer
+	treeView(&buf, objs, []unstructured.Unstructured{a}, treeOptions{})
+	if !strings.Contains(buf.String(), "↻ cycle back to ConfigMap/a") {
+		t.Fatalf("expected the cycle to be reported:\n%s", buf.String())
+	}
+
+	buf.Reset()
+	if err := structuredView(&buf, outputJSON, objs, []unstructured.Unstructured{a}, true, treeOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(buf.String(), `"cycle": true`) {
+		t.Fatalf("expected the cycle to be marked in the structured output:\n%s", buf.String())
+	}
+}
+
+func TestTreeViewCollapse(t *testing.T) {
+	noColor := color.NoColor
+	color.NoColor = true
+	defer func() { color.NoColor = noColor }()
+
+	newReplicaSet := func(name string, uid, owner types.UID, replicas int64) unstructured.Unstructured {
+		rs := newTestObject("apps/v1", "ReplicaSet", name, uid, owner)
+		if err := unstructured.SetNestedField(rs.Object, replicas, "spec", "replicas"); err != nil {
+			t.Fatal(err)
+		}
+		return rs
+	}
+	deploy := newTestObject("apps/v1", "Deployment", "app", "d1")
+	other := newTestObject("apps/v1", "Deployment", "other", "d2")
+	objs := newObjectDirectory([]unstructured.Unstructured{
+		deploy,
+		newReplicaSet("app-1", "rs1", "d1", 0),
+		newReplicaSet("app-2", "rs2", "d1", 0),
+		newReplicaSet("app-3", "rs3", "d1", 2),
+		newTestObject("v1", "Pod", "app-3-a", "p1", "rs3"),
+		newTestObject("v1", "Secret", "token", "s1", "d1"),
+		other,
+		newReplicaSet("other-1", "rs4", "d2", 1),
+	})
+
+	var buf bytes.Buffer
+	treeView(&buf, objs, []unstructured.Unstructured{deploy, other}, treeOptions{collapse: newKindSelector([]string{"rs"})})
+	out := buf.String()
+	for _, want := range []string{"├─ReplicaSet (3 more, 2 scaled to 0)", "└─Secret/token", "└─ReplicaSet (1 more)"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("output does not contain %q:\n%s", want, out)
+		}
+	}
+	if strings.Contains(out, "app-3-a") {
+		t.Errorf("children of collapsed objects are printed:\n%s", out)
+	}
+}
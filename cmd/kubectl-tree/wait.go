@@ -0,0 +1,114 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/fatih/color"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/klog"
+	"sigs.k8s.io/cli-utils/pkg/kstatus/status"
+)
+
+// Values of --wait-for.
+const (
+	waitForCurrent = "current"
+	waitForReady   = "ready"
+)
+
+var waitForConditions = []string{waitForCurrent, waitForReady}
+
+const (
+	// waitPollInterval is how long to wait between querying the objects again while waiting for the trees to converge.
+	waitPollInterval = 2 * time.Second
+
+	// defaultWaitTimeout is how long to wait for the trees to converge by default.
+	defaultWaitTimeout = 5 * time.Minute
+)
+
+// waitForTree queries the objects in the trees of the roots repeatedly until all of them reach the condition (current
+// or ready), printing the progress to stderr. If the APIs are already listed, the first check uses listed. It returns
+// the latest state of the trees, and an error listing the objects that have not converged if ctx is done first.
+func waitForTree(ctx context.Context, client dynamic.Interface, apis []apiResource, namespace string, labelSelector string,
+	listed *resourceList, roots []unstructured.Unstructured, relations []string, condition string, conditionTypes []string) (objectDirectory, []unstructured.Unstructured, error) {
+	var last string
+	for {
+		var apiObjects []unstructured.Unstructured
+		var err error
+		if listed != nil {
+			apiObjects, listed = listed.objects, nil
+		} else if apiObjects, err = getAllResources(client, apis, namespace, labelSelector); err != nil {
+			return objectDirectory{}, nil, fmt.Errorf("error while querying api objects: %w", err)
+		}
+		objs := newObjectDirectory(apiObjects)
+		objs.resolveRelations(relations)
+		roots = latestRoots(objs, roots)
+
+		total := len(treeObjects(objs, roots))
+		pending := pendingObjects(objs, roots, condition, conditionTypes)
+		msg := fmt.Sprintf("%d/%d objects %s", total-len(pending), total, condition)
+		if len(pending) > 0 {
+			msg += ", waiting for: " + strings.Join(pending, ", ")
+		}
+		if msg != last {
+			fmt.Fprintln(color.Error, gray.Sprintf("[%s] %s", time.Now().Format(time.TimeOnly), msg))
+			last = msg
+		}
+		if len(pending) == 0 {
+			return objs, roots, nil
+		}
+
+		select {
+		case <-ctx.Done():
+			if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+				err = fmt.Errorf("timed out waiting for %d object(s) to be %s: %s", len(pending), condition, strings.Join(pending, ", "))
+			} else {
+				err = fmt.Errorf("interrupted while waiting for %d object(s) to be %s: %s", len(pending), condition, strings.Join(pending, ", "))
+			}
+			return objs, roots, err
+		case <-time.After(waitPollInterval):
+		}
+	}
+}
+
+// pendingObjects describes the objects in the trees of the roots that have not reached the condition yet. With
+// current, all objects must have the Current kstatus. With ready, the objects that have one of the condition types
+// must have it set to True.
+func pendingObjects(objs objectDirectory, roots []unstructured.Unstructured, condition string, conditionTypes []string) []string {
+	var out []string
+	for _, obj := range treeObjects(objs, roots) {
+		var state string
+		switch condition {
+		case waitForCurrent:
+			res, err := status.Compute(&obj)
+			if err != nil {
+				klog.V(2).Infof("cannot compute status of %s/%s, not waiting for it: %v", obj.GetKind(), obj.GetName(), err)
+				continue
+			}
+			if res.Status != status.CurrentStatus {
+				state = res.Status.String()
+			}
+		case waitForReady:
+			if ready, _, _ := extractStatus(obj, conditionTypes); ready != "" && ready != "True" {
+				state = "Ready=" + string(ready)
+			}
+		}
+		if state != "" {
+			out = append(out, fmt.Sprintf("%s/%s (%s)", obj.GetKind(), obj.GetName(), state))
+		}
+	}
+	return out
+}
+
+// validateWaitFor returns an error if the --wait-for condition is not supported.
+func validateWaitFor(condition string) error {
+	if condition != "" && !slices.Contains(waitForConditions, condition) {
+		return fmt.Errorf("unknown condition %q (supported: %s)", condition, strings.Join(waitForConditions, ", "))
+	}
+	return nil
+}
@@ -0,0 +1,57 @@
+package main
+
+import (
+	"slices"
+	"testing"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+func TestPendingObjects(t *testing.T) {
+	deploy := newTestObject("apps/v1", "Deployment", "app", "d1")
+	deploy.Object["metadata"].(map[string]interface{})["generation"] = int64(2)
+	deploy.Object["spec"] = map[string]interface{}{"replicas": int64(1)}
+	deploy.Object["status"] = map[string]interface{}{
+		"observedGeneration": int64(2),
+		"replicas":           int64(1),
+		"updatedReplicas":    int64(1),
+		"readyReplicas":      int64(1),
+		"availableReplicas":  int64(1),
+		"conditions": []interface{}{
+			map[string]interface{}{"type": "Available", "status": "True"},
+			map[string]interface{}{"type": "Progressing", "status": "True", "reason": "NewReplicaSetAvailable"},
+		},
+	}
+	ready := newTestObject("v1", "Pod", "app-a", "p1", "d1")
+	ready.Object["status"] = map[string]interface{}{
+		"phase":      "Running",
+		"conditions": []interface{}{map[string]interface{}{"type": "Ready", "status": "True"}},
+	}
+	notReady := newTestObject("v1", "Pod", "app-b", "p2", "d1")
+	notReady.Object["status"] = map[string]interface{}{
+		"phase":      "Running",
+		"conditions": []interface{}{map[string]interface{}{"type": "Ready", "status": "False"}},
+	}
+	roots := []unstructured.Unstructured{deploy}
+
+	tests := []struct {
+		name      string
+		objs      []unstructured.Unstructured
+		condition string
+		want      []string
+	}{
+		{name: "current", objs: []unstructured.Unstructured{deploy, ready}, condition: waitForCurrent, want: nil},
+		{name: "in progress", objs: []unstructured.Unstructured{deploy, ready, notReady}, condition: waitForCurrent,
+			want: []string{"Pod/app-b (InProgress)"}},
+		{name: "not ready", objs: []unstructured.Unstructured{deploy, ready, notReady}, condition: waitForReady,
+			want: []string{"Pod/app-b (Ready=False)"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := pendingObjects(newObjectDirectory(tt.objs), roots, tt.condition, []string{"Ready"})
+			if !slices.Equal(got, tt.want) {
+				t.Fatalf("pendingObjects() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,274 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"slices"
+	"time"
+
+	"github.com/fatih/color"
+	"golang.org/x/term"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/klog"
+)
+
+const (
+	// redrawDelay is how long to wait for more events to arrive before re-rendering the tree.
+	redrawDelay = 250 * time.Millisecond
+
+	// watchRetryDelay is how long to wait before re-establishing a watch that failed.
+	watchRetryDelay = 2 * time.Second
+
+	clearScreen = "\033[H\033[2J"
+)
+
+// watchTree lists and then watches the specified APIs, keeps the object directory up to date and calls render with
+// the latest state of the trees whenever an object in them changes. The logical relationships between the objects are
+// resolved again before each redraw rather than on every event. If the APIs are already listed, the watches start
+// from listed instead. It returns when ctx is cancelled or all root objects are deleted.
+func watchTree(ctx context.Context, client dynamic.Interface, apis []apiResource, namespace string, labelSelector string,
+	listed *resourceList, roots []unstructured.Unstructured, relations []string, render func(objectDirectory, []unstructured.Unstructured) error) error {
+	if listed == nil {
+		apiObjects, versions, err := listAllResources(client, apis, namespace, labelSelector)
+		if err != nil {
+			return fmt.Errorf("error while querying api objects: %w", err)
+		}
+		listed = &resourceList{objects: apiObjects, versions: versions}
+	}
+	versions := listed.versions
+	objs := newObjectDirectory(listed.objects)
+	if len(relations) > 0 {
+		objs.resolveRelations(relations)
+	}
+	rootIDs := make(map[types.UID]bool)
+	for _, root := range roots {
+		rootIDs[root.GetUID()] = true
+	}
+	if err := render(objs, latestRoots(objs, roots)); err != nil {
+		return err
+	}
+
+	events := make(chan watch.Event)
+	for _, api := range apis {
+		rv, ok := versions[api.GroupVersionResource()]
+		if !ok {
+			// not listed (non-namespaced or forbidden), so there's nothing to watch
+			continue
+		}
+		go watchAPI(ctx, client, api, namespace, labelSelector, rv, events)
+	}
+	klog.V(2).Infof("started watching %d apis", len(versions))
+
+	var redraw <-chan time.Time
+	// the trees are redrawn if an object in them changed, or with relations, if one of the unresolved objects that
+	// changed is related to them once the relations are resolved again at the next redraw
+	var changedTree bool
+	var unresolved []types.UID
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case ev := <-events:
+			obj, ok := ev.Object.(*unstructured.Unstructured)
+			if !ok {
+				continue
+			}
+			old, existed := objs.items[obj.GetUID()]
+			changed := existed && inTree(objs, rootIDs, old)
+			switch ev.Type {
+			case watch.Added, watch.Modified:
+				objs.upsert(*obj)
+				changed = changed || inTree(objs, rootIDs, *obj)
+				if !changed && len(relations) > 0 {
+					unresolved = append(unresolved, obj.GetUID())
+				}
+			case watch.Deleted:
+				objs.remove(*obj)
+				if rootIDs[obj.GetUID()] {
+					roots = slices.DeleteFunc(roots, func(v unstructured.Unstructured) bool { return v.GetUID() == obj.GetUID() })
+					delete(rootIDs, obj.GetUID())
+					fmt.Printf("%s/%s was deleted.\n", obj.GetKind(), obj.GetName())
+					if len(roots) == 0 {
+						return nil
+					}
+					changed = true
+				}
+			}
+			klog.V(4).Infof("[watch] %s %s/%s (in tree: %v)", ev.Type, obj.GetKind(), obj.GetName(), changed)
+			changedTree = changedTree || changed
+			if (changed || len(unresolved) > 0) && redraw == nil {
+				redraw = time.After(redrawDelay)
+			}
+		case <-redraw:
+			redraw = nil
+			if len(relations) > 0 {
+				objs.resolveRelations(relations)
+			}
+			for _, id := range unresolved {
+				if obj, ok := objs.items[id]; ok && inTree(objs, rootIDs, obj) {
+					changedTree = true
+				}
+			}
+			unresolved = nil
+			if !changedTree {
+				continue
+			}
+			changedTree = false
+			if err := render(objs, latestRoots(objs, roots)); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// watchAPI watches the specified API resource starting at resource version rv and sends events to out until ctx is
+// cancelled. Watches that are closed by the server are re-established.
+func watchAPI(ctx context.Context, client dynamic.Interface, api apiResource, namespace, labelSelector, rv string, out chan<- watch.Event) {
+	var ri dynamic.ResourceInterface = client.Resource(api.GroupVersionResource())
+	if namespace != "" {
+		ri = client.Resource(api.GroupVersionResource()).Namespace(namespace)
+	}
+	for ctx.Err() == nil {
+		w, err := ri.Watch(ctx, metav1.ListOptions{
+			ResourceVersion:     rv,
+			LabelSelector:       labelSelector,
+			AllowWatchBookmarks: true,
+		})
+		if err != nil {
+			if apierrors.IsForbidden(err) || apierrors.IsMethodNotSupported(err) {
+				klog.V(2).Infof("[watch] cannot watch %s, omitting from updates: %v", api.GroupVersionResource(), err)
+				return
+			}
+			klog.V(2).Infof("[watch] failed to watch %s, retrying: %v", api.GroupVersionResource(), err)
+			sleep(ctx, watchRetryDelay)
+			continue
+		}
+		rv = forwardEvents(ctx, api, w, rv, out)
+		w.Stop()
+	}
+}
+
+// forwardEvents sends the events from w to out until the watch is closed, and returns the last seen resource version
+// to resume from.
+func forwardEvents(ctx context.Context, api apiResource, w watch.Interface, rv string, out chan<- watch.Event) string {
+	for {
+		select {
+		case <-ctx.Done():
+			return rv
+		case ev, ok := <-w.ResultChan():
+			if !ok {
+				klog.V(4).Infof("[watch] watch closed for %s", api.GroupVersionResource())
+				return rv
+			}
+			switch ev.Type {
+			case watch.Error:
+				err := apierrors.FromObject(ev.Object)
+				if apierrors.IsResourceExpired(err) || apierrors.IsGone(err) {
+					// start over from the most recent version, objects deleted in between will be missed
+					klog.V(2).Infof("[watch] resource version expired for %s, restarting watch", api.GroupVersionResource())
+					return ""
+				}
+				klog.V(2).Infof("[watch] error watching %s: %v", api.GroupVersionResource(), err)
+				return rv
+			case watch.Bookmark:
+				if obj, ok := ev.Object.(*unstructured.Unstructured); ok {
+					rv = obj.GetResourceVersion()
+				}
+				continue
+			}
+			if obj, ok := ev.Object.(*unstructured.Unstructured); ok {
+				rv = obj.GetResourceVersion()
+			}
+			select {
+			case out <- ev:
+			case <-ctx.Done():
+				return rv
+			}
+		}
+	}
+}
+
+// latestRoots returns the latest versions of the root objects in objs, or the original ones if they are not found.
+func latestRoots(objs objectDirectory, roots []unstructured.Unstructured) []unstructured.Unstructured {
+	out := make([]unstructured.Unstructured, 0, len(roots))
+	for _, root := range roots {
+		if v, ok := objs.items[root.GetUID()]; ok {
+			root = v
+		}
+		out = append(out, root)
+	}
+	return out
+}
+
+// inTree reports whether obj is one of the root objects or their descendants, by following its owners and the objects
+// it's related to through logical relationships in objs.
+func inTree(objs objectDirectory, roots map[types.UID]bool, obj unstructured.Unstructured) bool {
+	visited := make(map[types.UID]bool)
+	queue := []unstructured.Unstructured{obj}
+	for len(queue) > 0 {
+		cur := queue[0]
+		queue = queue[1:]
+		if roots[cur.GetUID()] {
+			return true
+		}
+		var parents []types.UID
+		for _, ref := range cur.GetOwnerReferences() {
+			parents = append(parents, ref.UID)
+		}
+		for from, to := range objs.relations {
+			if _, ok := to[cur.GetUID()]; ok {
+				parents = append(parents, from)
+			}
+		}
+		for _, id := range parents {
+			if visited[id] {
+				continue
+			}
+			visited[id] = true
+			if roots[id] {
+				return true
+			}
+			if parent, ok := objs.items[id]; ok {
+				queue = append(queue, parent)
+			}
+		}
+	}
+	return false
+}
+
+func sleep(ctx context.Context, d time.Duration) {
+	select {
+	case <-ctx.Done():
+	case <-time.After(d):
+	}
+}
+
+// watchRenderer returns a function that prints the tree each time it changes. On terminals, the screen is cleared
+// before the tree is printed again.
+func watchRenderer(outputFormat string, single bool, opts treeOptions) func(objectDirectory, []unstructured.Unstructured) error {
+	tty := term.IsTerminal(int(os.Stdout.Fd()))
+	return func(objs objectDirectory, roots []unstructured.Unstructured) error {
+		switch outputFormat {
+		case "":
+		case outputYAML:
+			fmt.Println("---")
+			fallthrough
+		default:
+			return printTrees(outputFormat, objs, roots, single, opts, "")
+		}
+		if tty {
+			fmt.Fprint(color.Output, clearScreen)
+		} else {
+			fmt.Fprintln(color.Output)
+		}
+		fmt.Fprintf(color.Output, "%s\n\n", gray.Sprintf("Watching %d tree(s), last update: %s", len(roots), time.Now().Format(time.TimeOnly)))
+		treeView(color.Output, opts.filter.apply(objs, roots, opts.conditionTypes), roots, opts)
+		return nil
+	}
+}
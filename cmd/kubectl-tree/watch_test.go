@@ -0,0 +1,233 @@
+package main
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/watch"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+	k8stesting "k8s.io/client-go/testing"
+)
+
+func TestInTree(t *testing.T) {
+	deploy := newTestObject("apps/v1", "Deployment", "app", "d1")
+	pod := newTestObject("v1", "Pod", "app-1-a", "p1", "rs1")
+	pod.Object["spec"] = map[string]interface{}{
+		"volumes": []interface{}{
+			map[string]interface{}{"name": "config", "configMap": map[string]interface{}{"name": "app-config"}},
+		},
+	}
+	cm := newTestObject("v1", "ConfigMap", "app-config", "cm1")
+	other := newTestObject("v1", "ConfigMap", "other", "cm2")
+	objs := newObjectDirectory([]unstructured.Unstructured{
+		deploy,
+		newTestObject("apps/v1", "ReplicaSet", "app-1", "rs1", "d1"),
+		pod,
+		cm,
+		other,
+	})
+	roots := map[types.UID]bool{"d1": true}
+
+	if !inTree(objs, roots, deploy) || !inTree(objs, roots, pod) {
+		t.Error("the root and its descendants are not in the tree")
+	}
+	if inTree(objs, roots, cm) {
+		t.Error("related ConfigMap is in the tree without --relations")
+	}
+	objs.resolveRelations([]string{"config"})
+	if !inTree(objs, roots, cm) {
+		t.Error("related ConfigMap is not in the tree with --relations=config")
+	}
+	if inTree(objs, roots, other) {
+		t.Error("unrelated ConfigMap is in the tree")
+	}
+}
+
+func TestForwardEvents(t *testing.T) {
+	pods := apiResource{gv: schema.GroupVersion{Version: "v1"}, r: metav1.APIResource{Name: "pods", Kind: "Pod", Namespaced: true}}
+	withVersion := func(obj unstructured.Unstructured, rv string) *unstructured.Unstructured {
+		out := obj.DeepCopy()
+		out.SetResourceVersion(rv)
+		return out
+	}
+	pod := newTestObject("v1", "Pod", "app-1-a", "p1")
+
+	// bookmarks only move the resource version forward, the watch resumes from the last event when it's closed
+	w := watch.NewFake()
+	out := make(chan watch.Event, 10)
+	go func() {
+		w.Action(watch.Bookmark, withVersion(pod, "5"))
+		w.Add(withVersion(pod, "6"))
+		w.Action(watch.Bookmark, withVersion(pod, "7"))
+		w.Stop()
+	}()
+	if rv := forwardEvents(context.Background(), pods, w, "1", out); rv != "7" {
+		t.Errorf("got resource version %q after the watch is closed, want 7", rv)
+	}
+	if len(out) != 1 {
+		t.Fatalf("got %d forwarded events, want 1", len(out))
+	}
+	if ev := <-out; ev.Type != watch.Added {
+		t.Errorf("got forwarded %s event, want %s", ev.Type, watch.Added)
+	}
+
+	// expired resource versions restart the watch from the most recent version
+	for _, err := range []*apierrors.StatusError{apierrors.NewResourceExpired("too old"), apierrors.NewGone("gone")} {
+		w = watch.NewFake()
+		go w.Error(&err.ErrStatus)
+		if rv := forwardEvents(context.Background(), pods, w, "5", out); rv != "" {
+			t.Errorf("%v: got resource version %q, want the watch to restart from the most recent version", err, rv)
+		}
+	}
+	// other errors resume from the last seen version
+	w = watch.NewFake()
+	go w.Error(&apierrors.NewInternalError(context.DeadlineExceeded).ErrStatus)
+	if rv := forwardEvents(context.Background(), pods, w, "5", out); rv != "5" {
+		t.Errorf("got resource version %q after an error, want 5", rv)
+	}
+}
+
+func TestWatchTree(t *testing.T) {
+	newAPI := func(group, resource, kind string) apiResource {
+		return apiResource{
+			gv: schema.GroupVersion{Group: group, Version: "v1"},
+			r:  metav1.APIResource{Name: resource, Kind: kind, Namespaced: true, Verbs: []string{"list", "watch"}},
+		}
+	}
+	deployments := newAPI("apps", "deployments", "Deployment")
+	pods := newAPI("", "pods", "Pod")
+
+	deploy := newTestObject("apps/v1", "Deployment", "app", "d1")
+	pod := newTestObject("v1", "Pod", "app-a", "p1", "d1")
+	client := dynamicfake.NewSimpleDynamicClientWithCustomListKinds(runtime.NewScheme(), map[schema.GroupVersionResource]string{
+		deployments.GroupVersionResource(): "DeploymentList",
+		pods.GroupVersionResource():        "PodList",
+	}, &deploy, &pod)
+	watchers := map[string]*watch.FakeWatcher{"deployments": watch.NewFake(), "pods": watch.NewFake()}
+	client.PrependWatchReactor("*", func(action k8stesting.Action) (bool, watch.Interface, error) {
+		return true, watchers[action.GetResource().Resource], nil
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	renders := make(chan []string, 10)
+	done := make(chan error, 1)
+	go func() {
+		done <- watchTree(ctx, client, []apiResource{deployments, pods}, "", "", nil, []unstructured.Unstructured{deploy}, nil,
+			func(objs objectDirectory, roots []unstructured.Unstructured) error {
+				var names []string
+				for _, obj := range treeObjects(objs, roots) {
+					names = append(names, obj.GetName())
+				}
+				renders <- names
+				return nil
+			})
+	}()
+	waitRender := func() []string {
+		t.Helper()
+		select {
+		case names := <-renders:
+			return names
+		case <-time.After(5 * time.Second):
+			t.Fatal("the tree was not rendered")
+			return nil
+		}
+	}
+
+	if got := waitRender(); len(got) != 2 {
+		t.Fatalf("initial render has %v, want the deployment and the pod", got)
+	}
+
+	// objects outside of the tree don't trigger a redraw
+	unrelated := newTestObject("v1", "Pod", "other", "p2")
+	watchers["pods"].Add(&unrelated)
+	select {
+	case got := <-renders:
+		t.Fatalf("unexpected render after an unrelated change: %v", got)
+	case <-time.After(2 * redrawDelay):
+	}
+
+	added := newTestObject("v1", "Pod", "app-b", "p3", "d1")
+	watchers["pods"].Add(&added)
+	if got := waitRender(); len(got) != 3 {
+		t.Fatalf("render after a new pod in the tree has %v, want 3 objects", got)
+	}
+
+	// deleting the only root ends the watch
+	watchers["deployments"].Delete(&deploy)
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatal(err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("the watch did not end after the root was deleted")
+	}
+}
+
+func TestWatchTreeRelations(t *testing.T) {
+	pods := apiResource{gv: schema.GroupVersion{Version: "v1"}, r: metav1.APIResource{Name: "pods", Kind: "Pod", Namespaced: true, Verbs: []string{"list", "watch"}}}
+	configMaps := apiResource{gv: schema.GroupVersion{Version: "v1"}, r: metav1.APIResource{Name: "configmaps", Kind: "ConfigMap", Namespaced: true, Verbs: []string{"list", "watch"}}}
+
+	pod := newTestObject("v1", "Pod", "app-a", "p1")
+	pod.Object["spec"] = map[string]interface{}{
+		"volumes": []interface{}{
+			map[string]interface{}{"name": "config", "configMap": map[string]interface{}{"name": "app-config"}},
+		},
+	}
+	client := dynamicfake.NewSimpleDynamicClientWithCustomListKinds(runtime.NewScheme(), map[schema.GroupVersionResource]string{
+		pods.GroupVersionResource():       "PodList",
+		configMaps.GroupVersionResource(): "ConfigMapList",
+	}, &pod)
+	watchers := map[string]*watch.FakeWatcher{"pods": watch.NewFake(), "configmaps": watch.NewFake()}
+	client.PrependWatchReactor("*", func(action k8stesting.Action) (bool, watch.Interface, error) {
+		return true, watchers[action.GetResource().Resource], nil
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	renders := make(chan int, 10)
+	go func() {
+		_ = watchTree(ctx, client, []apiResource{pods, configMaps}, "", "", nil, []unstructured.Unstructured{pod}, []string{"config"},
+			func(objs objectDirectory, roots []unstructured.Unstructured) error {
+				renders <- len(treeObjects(objs, roots))
+				return nil
+			})
+	}()
+	waitRender := func() int {
+		t.Helper()
+		select {
+		case n := <-renders:
+			return n
+		case <-time.After(5 * time.Second):
+			t.Fatal("the tree was not rendered")
+			return 0
+		}
+	}
+	if n := waitRender(); n != 1 {
+		t.Fatalf("initial render has %d objects, want 1", n)
+	}
+
+	// unrelated objects don't trigger a redraw once the relations are resolved
+	other := newTestObject("v1", "ConfigMap", "other", "cm2")
+	watchers["configmaps"].Add(&other)
+	select {
+	case n := <-renders:
+		t.Fatalf("unexpected render with %d objects after an unrelated change", n)
+	case <-time.After(2 * redrawDelay):
+	}
+
+	// a new ConfigMap used by the pod is only related to the tree after the relations are resolved
+	cm := newTestObject("v1", "ConfigMap", "app-config", "cm1")
+	watchers["configmaps"].Add(&cm)
+	if n := waitRender(); n != 2 {
+		t.Fatalf("render after the ConfigMap of the pod is created has %d objects, want 2", n)
+	}
+}
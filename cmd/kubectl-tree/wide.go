@@ -0,0 +1,85 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/utils/ptr"
+)
+
+const outputWide = "wide"
+
+// wideDetails returns the most relevant details of an object for its kind, e.g. the node, IP, restarts and phase of
+// Pods, or an empty string for kinds that don't have any.
+func wideDetails(obj unstructured.Unstructured) string {
+	var details []string
+	add := func(key string, value any) {
+		if s := fmt.Sprint(value); s != "" {
+			details = append(details, key+"="+s)
+		}
+	}
+
+	switch obj.GroupVersionKind().GroupKind().String() {
+	case "Pod":
+		add("node", nestedString(obj.Object, "spec", "nodeName"))
+		add("ip", nestedString(obj.Object, "status", "podIP"))
+		add("restarts", podRestarts(obj))
+		add("phase", nestedString(obj.Object, "status", "phase"))
+	case "Deployment.apps", "ReplicaSet.apps", "StatefulSet.apps", "ReplicationController":
+		desired, ok, _ := unstructured.NestedInt64(obj.Object, "spec", "replicas")
+		if !ok {
+			desired = 1 // the default of the controllers
+		}
+		add("desired", desired)
+		add("ready", nestedInt64(obj.Object, "status", "readyReplicas"))
+	case "DaemonSet.apps":
+		add("desired", nestedInt64(obj.Object, "status", "desiredNumberScheduled"))
+		add("ready", nestedInt64(obj.Object, "status", "numberReady"))
+	case "Job.batch":
+		completions, ok, _ := unstructured.NestedInt64(obj.Object, "spec", "completions")
+		if !ok {
+			completions = 1
+		}
+		add("completions", fmt.Sprintf("%d/%d", nestedInt64(obj.Object, "status", "succeeded"), completions))
+		if n := nestedInt64(obj.Object, "status", "active"); n > 0 {
+			add("active", n)
+		}
+		if n := nestedInt64(obj.Object, "status", "failed"); n > 0 {
+			add("failed", n)
+		}
+	case "PersistentVolumeClaim":
+		add("capacity", nestedString(obj.Object, "status", "capacity", "storage"))
+		add("class", nestedString(obj.Object, "spec", "storageClassName"))
+	}
+	return strings.Join(details, " ")
+}
+
+// podRestarts returns the total number of restarts of the containers of a Pod.
+func podRestarts(pod unstructured.Unstructured) int64 {
+	var n int64
+	for _, field := range []string{"initContainerStatuses", "containerStatuses"} {
+		statuses, _, _ := unstructured.NestedSlice(pod.Object, "status", field)
+		for _, s := range statuses {
+			n += nestedInt64(s, "restartCount")
+		}
+	}
+	return n
+}
+
+// ownerRefFlags describes the flags of the ownerReference between an object and its parent in the tree, e.g.
+// "controller,blockOwnerDeletion".
+func ownerRefFlags(ref *metav1.OwnerReference) string {
+	if ref == nil {
+		return ""
+	}
+	var flags []string
+	if ptr.Deref(ref.Controller, false) {
+		flags = append(flags, "controller")
+	}
+	if ptr.Deref(ref.BlockOwnerDeletion, false) {
+		flags = append(flags, "blockOwnerDeletion")
+	}
+	return strings.Join(flags, ",")
+}
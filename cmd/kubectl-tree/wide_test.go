@@ -0,0 +1,64 @@
+package main
+
+import (
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/utils/ptr"
+)
+
+func TestWideDetails(t *testing.T) {
+	pod := newTestObject("v1", "Pod", "app", "p1")
+	pod.Object["spec"] = map[string]interface{}{"nodeName": "node-1"}
+	pod.Object["status"] = map[string]interface{}{
+		"phase": "Running",
+		"podIP": "10.0.0.1",
+		"containerStatuses": []interface{}{
+			map[string]interface{}{"restartCount": int64(2)},
+			map[string]interface{}{"restartCount": int64(1)},
+		},
+	}
+	rs := newTestObject("apps/v1", "ReplicaSet", "app", "rs1")
+	rs.Object["spec"] = map[string]interface{}{"replicas": int64(3)}
+	rs.Object["status"] = map[string]interface{}{"readyReplicas": int64(2)}
+	job := newTestObject("batch/v1", "Job", "migrate", "j1")
+	job.Object["spec"] = map[string]interface{}{"completions": int64(3)}
+	job.Object["status"] = map[string]interface{}{"succeeded": int64(1), "active": int64(1)}
+	pvc := newTestObject("v1", "PersistentVolumeClaim", "data", "pvc1")
+	pvc.Object["spec"] = map[string]interface{}{"storageClassName": "standard"}
+	pvc.Object["status"] = map[string]interface{}{"capacity": map[string]interface{}{"storage": "10Gi"}}
+
+	tests := []struct {
+		obj  unstructured.Unstructured
+		want string
+	}{
+		{pod, "node=node-1 ip=10.0.0.1 restarts=3 phase=Running"},
+		{rs, "desired=3 ready=2"},
+		{job, "completions=1/3 active=1"},
+		{pvc, "capacity=10Gi class=standard"},
+		{newTestObject("v1", "ConfigMap", "config", "cm1"), ""},
+	}
+	for _, tt := range tests {
+		if got := wideDetails(tt.obj); got != tt.want {
+			t.Errorf("wideDetails(%s) = %q, want %q", tt.obj.GetKind(), got, tt.want)
+		}
+	}
+}
+
+func TestOwnerRefFlags(t *testing.T) {
+	tests := []struct {
+		ref  *metav1.OwnerReference
+		want string
+	}{
+		{nil, ""},
+		{&metav1.OwnerReference{}, ""},
+		{&metav1.OwnerReference{Controller: ptr.To(true)}, "controller"},
+		{&metav1.OwnerReference{Controller: ptr.To(true), BlockOwnerDeletion: ptr.To(true)}, "controller,blockOwnerDeletion"},
+	}
+	for _, tt := range tests {
+		if got := ownerRefFlags(tt.ref); got != tt.want {
+			t.Errorf("ownerRefFlags(%+v) = %q, want %q", tt.ref, got, tt.want)
+		}
+	}
+}